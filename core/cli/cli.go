@@ -0,0 +1,235 @@
+package cli
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/czz/oblivion/core/config"
+    "github.com/czz/oblivion/core/secret"
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/export"
+    "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/stream"
+)
+
+// Exit codes returned by Run.
+const (
+    ExitOK     = 0 // Module ran to completion
+    ExitError  = 1 // Option, run or save error
+    ExitUsage  = 2 // Invalid command line
+    ExitCancel = 3 // Run interrupted by signal or timeout
+)
+
+// optionFlags collects repeated -o NAME=VALUE flags in order.
+type optionFlags []string
+
+func (o *optionFlags) String() string {
+    return strings.Join(*o, ",")
+}
+
+func (o *optionFlags) Set(v string) error {
+    if !strings.Contains(v, "=") {
+        return fmt.Errorf("option must be in the form NAME=VALUE: %s", v)
+    }
+    *o = append(*o, v)
+    return nil
+}
+
+// Run executes a single module non-interactively and returns the process exit code.
+// args are the command line arguments following "run": <module> [flags].
+func Run(args []string) int {
+    t := tui.NewTui()
+
+    if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+        fmt.Fprintln(os.Stderr, "Usage: oblivion run <module> [-o NAME=VALUE ...] [--format table|json|txt] [--output file [--output-format f]] [--timeout 10m] [--scope file] [--workspace name]")
+        return ExitUsage
+    }
+    prompt := args[0]
+
+    var opts optionFlags
+    fs := flag.NewFlagSet("run "+prompt, flag.ContinueOnError)
+    fs.Var(&opts, "o", "module option as NAME=VALUE (repeatable)")
+    format := fs.String("format", "table", "output format: table, json or txt")
+    output := fs.String("output", "", "save results to file using the module's Save")
+    outputFormat := fs.String("output-format", "", "save --output in this format: "+strings.Join(export.Formats, ", "))
+    timeout := fs.Duration("timeout", 0, "abort the run after this duration (0 means no limit)")
+    quiet := fs.Bool("quiet", false, "do not print results to stdout")
+    scopeFile := fs.String("scope", "", "restrict the run to the targets in this scope file")
+    wsName := fs.String("workspace", "", "store the run in this workspace, for diff")
+    if err := fs.Parse(args[1:]); err != nil {
+        return ExitUsage
+    }
+
+    switch *format {
+    case "table", "json", "txt":
+    default:
+        fmt.Fprintln(os.Stderr, t.Red("Unknown format: "+*format))
+        return ExitUsage
+    }
+    if *outputFormat != "" && !export.Valid(*outputFormat) {
+        fmt.Fprintln(os.Stderr, t.Red("Unknown output format: "+*outputFormat))
+        return ExitUsage
+    }
+
+    // Global options (setg) apply to non-interactive runs too
+    if cfg, err := config.Load(); err != nil {
+        fmt.Fprintln(os.Stderr, t.Yellow("Ignoring configuration: "+err.Error()))
+    } else {
+        cfg.ApplyGlobals()
+    }
+    option.SetSecretLookup(secret.Get)
+
+    manager := modules.LoadModules()
+    module, ok := manager.Get(prompt)
+    if !ok {
+        fmt.Fprintln(os.Stderr, t.Red("Module not found: "+prompt))
+        return ExitError
+    }
+
+    // Apply options in the order they were given, stopping at the first error
+    for _, o := range opts {
+        kv := strings.SplitN(o, "=", 2)
+        result := module.Set(kv[0], kv[1])
+        if len(result) != 2 || result[0] == "Error" {
+            reason := "invalid value"
+            if len(result) == 2 {
+                reason = result[1]
+            }
+            fmt.Fprintln(os.Stderr, t.Red(fmt.Sprintf("Error setting %s: %s", kv[0], reason)))
+            return ExitError
+        }
+        fmt.Fprintln(os.Stderr, t.Yellow(result[0]+" => "+result[1]))
+    }
+
+    if errs := modules.Validate(module); len(errs) > 0 {
+        for _, err := range errs {
+            fmt.Fprintln(os.Stderr, t.Red("Error: "+err.Error()))
+        }
+        return ExitError
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    if *timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, *timeout)
+        defer cancel()
+    }
+
+    if *scopeFile != "" {
+        sc := scope.New()
+        if _, err := sc.Load(*scopeFile); err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error loading scope: "+err.Error()))
+            return ExitError
+        }
+        var mu sync.Mutex
+        seen := make(map[string]bool)
+        ctx = scope.WithScope(ctx, sc, func(target string) {
+            mu.Lock()
+            defer mu.Unlock()
+            if !seen[target] {
+                seen[target] = true
+                fmt.Fprintln(os.Stderr, t.Yellow("Skipped out of scope target: "+target))
+            }
+        })
+    }
+
+    // Store the rows in the workspace as they are found, like runs of the REPL
+    state := workspace.RunFailed
+    if *wsName != "" {
+        ws, err := workspace.Create(*wsName)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error opening workspace: "+err.Error()))
+            return ExitError
+        }
+        run, err := ws.CreateRun(prompt)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error storing run: "+err.Error()))
+            return ExitError
+        }
+        defer func() {
+            if err := run.Finish(state); err != nil {
+                fmt.Fprintln(os.Stderr, t.Red("Error storing run: "+err.Error()))
+            }
+        }()
+        ctx = stream.WithEmitter(ctx, func(row []string) {
+            if err := run.Write(row); err != nil {
+                fmt.Fprintln(os.Stderr, t.Red("Error storing run: "+err.Error()))
+            }
+        })
+        fmt.Fprintln(os.Stderr, t.Yellow("Storing run "+run.ID+" in workspace "+ws.Name))
+    }
+
+    started := time.Now()
+    module.Start()
+    results := module.Run(ctx)
+    module.Stop()
+
+    code, state := ExitOK, workspace.RunFinished
+    if err := ctx.Err(); err != nil {
+        fmt.Fprintln(os.Stderr, t.Yellow(fmt.Sprintf("Run of %s aborted after %s: %s", prompt, time.Since(started).Round(time.Millisecond), err)))
+        code, state = ExitCancel, workspace.RunCancelled
+    }
+    if modules.IsErrorResult(results) {
+        fmt.Fprintln(os.Stderr, t.Red("Module "+prompt+" reported an error"))
+        code, state = ExitError, workspace.RunFailed
+    }
+
+    if !*quiet {
+        if err := writeResults(os.Stdout, t, *format, results); err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error writing results: "+err.Error()))
+            return ExitError
+        }
+    }
+
+    if *output != "" {
+        var err error
+        if *outputFormat != "" {
+            err = export.Save(*output, *outputFormat, modules.ExportData(module))
+        } else {
+            err = module.Save(*output)
+        }
+        if err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error saving: "+err.Error()))
+            return ExitError
+        }
+        fmt.Fprintln(os.Stderr, t.Green("Results saved to: "+*output))
+    }
+
+    return code
+}
+
+// writeResults renders results to w in the requested format.
+func writeResults(w io.Writer, t *tui.Tui, format string, results [][]string) error {
+    switch format {
+    case "json":
+        if results == nil {
+            results = [][]string{}
+        }
+        encoder := json.NewEncoder(w)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(results)
+    case "txt":
+        for _, row := range results {
+            if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+                return err
+            }
+        }
+        return nil
+    default:
+        _, err := fmt.Fprint(w, t.Table(&tui.Table{LineSeparator: false, Padding: 1}, results))
+        return err
+    }
+}
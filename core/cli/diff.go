@@ -0,0 +1,93 @@
+package cli
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/modules"
+)
+
+// Diff compares two stored runs of a module and returns the process exit code.
+// args are the command line arguments following "diff": <module> <runA> [runB] [flags].
+func Diff(args []string) int {
+    t := tui.NewTui()
+    usage := "Usage: oblivion diff <module> <runA> [runB] [--format table|json] [--workspace name]"
+
+    // Flags may follow the run IDs
+    var positional []string
+    for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+    fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+    format := fs.String("format", "table", "output format: table or json")
+    wsName := fs.String("workspace", workspace.Current(), "workspace holding the runs")
+    if err := fs.Parse(args); err != nil {
+        return ExitUsage
+    }
+    positional = append(positional, fs.Args()...)
+    if len(positional) < 2 || len(positional) > 3 {
+        fmt.Fprintln(os.Stderr, usage)
+        return ExitUsage
+    }
+    if *format != "table" && *format != "json" {
+        fmt.Fprintln(os.Stderr, t.Red("Unknown format: "+*format))
+        return ExitUsage
+    }
+    prompt := positional[0]
+
+    module, ok := modules.LoadModules().Get(prompt)
+    if !ok {
+        fmt.Fprintln(os.Stderr, t.Red("Module not found: "+prompt))
+        return ExitError
+    }
+    if !workspace.Exists(*wsName) {
+        fmt.Fprintln(os.Stderr, t.Red("Workspace not found: "+*wsName))
+        return ExitError
+    }
+    ws, err := workspace.Open(*wsName)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, t.Red("Error opening workspace: "+err.Error()))
+        return ExitError
+    }
+
+    refs := append(positional[1:], "latest")
+    from, before, err := ws.LoadRunRef(prompt, refs[0])
+    if err != nil {
+        fmt.Fprintln(os.Stderr, t.Red("Error: "+err.Error()))
+        return ExitError
+    }
+    to, after, err := ws.LoadRunRef(prompt, refs[1])
+    if err != nil {
+        fmt.Fprintln(os.Stderr, t.Red("Error: "+err.Error()))
+        return ExitError
+    }
+    d, err := modules.Compare(module, before, after)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, t.Red("Error: "+err.Error()))
+        return ExitError
+    }
+    d.From, d.To = from, to
+
+    if *format == "json" {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(d); err != nil {
+            fmt.Fprintln(os.Stderr, t.Red("Error writing diff: "+err.Error()))
+            return ExitError
+        }
+        return ExitOK
+    }
+
+    fmt.Fprintln(os.Stderr, t.Yellow(fmt.Sprintf("%s: %s -> %s", prompt, from, to)))
+    if len(d.Changes) > 0 {
+        fmt.Print(t.Table(&tui.Table{LineSeparator: false, Padding: 1}, d.Table()))
+    }
+    fmt.Fprintln(os.Stderr, t.Green(d.Summary()))
+    return ExitOK
+}
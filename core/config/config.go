@@ -0,0 +1,75 @@
+package config
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+
+    "github.com/czz/oblivion/core/notify"
+    "github.com/czz/oblivion/utils/option"
+)
+
+// Config is the user configuration stored in ~/.oblivion/config.
+type Config struct {
+    Globals map[string]string `json:"globals"`          // Global option values (setg)
+    Notify  []notify.Sink     `json:"notify,omitempty"` // Notification sinks (notify add)
+}
+
+// Path returns the location of the configuration file.
+func Path() (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion", "config"), nil
+}
+
+// Load reads the configuration file; a missing file yields an empty configuration.
+func Load() (*Config, error) {
+    cfg := &Config{Globals: make(map[string]string)}
+    path, err := Path()
+    if err != nil {
+        return cfg, err
+    }
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return cfg, nil
+    }
+    if err != nil {
+        return cfg, err
+    }
+    if err := json.Unmarshal(data, cfg); err != nil {
+        return cfg, err
+    }
+    if cfg.Globals == nil {
+        cfg.Globals = make(map[string]string)
+    }
+    return cfg, nil
+}
+
+// Save writes the configuration file.
+func (c *Config) Save() error {
+    path, err := Path()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(c, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+// ApplyGlobals makes the global option values of the configuration active.
+func (c *Config) ApplyGlobals() {
+    for name, value := range c.Globals {
+        option.SetGlobal(name, value)
+    }
+}
@@ -0,0 +1,295 @@
+package jobs
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/progress"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/stream"
+)
+
+// State is the lifecycle state of a job.
+type State string
+
+// Job states.
+const (
+    Running   State = "running"
+    Finished  State = "finished"
+    Cancelled State = "cancelled"
+    Failed    State = "failed"
+)
+
+// Job is a single run of a module instance.
+type Job struct {
+    ID      int               // Sequential job identifier
+    Module  string            // Prompt of the module
+    Options map[string]string // Option values when the job was started
+    Started time.Time         // When the job was started
+    RunID   string            // Identifier of the stored run, if any
+
+    instance modules.Module
+    cancel   context.CancelFunc
+    done     chan struct{}
+
+    mu      sync.Mutex
+    state   State
+    ended   time.Time
+    err     string
+    results [][]string
+    streamed [][]string
+    skipped []string
+    killed  bool
+}
+
+// Hooks are optional callbacks invoked while a job runs.
+type Hooks struct {
+    RunID string       // Stored in Job.RunID
+    Scope *scope.Scope // Targets the module may contact, nil for no restriction
+
+    // Row is called for every result row, as soon as the module emits it. Rows of
+    // modules that do not stream are passed once the run has ended.
+    Row func(job *Job, row []string)
+
+    // Finish is called once the module has returned and before waiters are
+    // released, so results can be collected from the instance.
+    Finish func(job *Job)
+}
+
+// Instance returns the module instance run by the job.
+func (j *Job) Instance() modules.Module {
+    return j.instance
+}
+
+// State returns the current state of the job.
+func (j *Job) State() State {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return j.state
+}
+
+// Err returns the error reported by a failed job.
+func (j *Job) Err() string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return j.err
+}
+
+// Elapsed returns the running time of the job, up to now while it is running.
+func (j *Job) Elapsed() time.Duration {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if j.ended.IsZero() {
+        return time.Since(j.Started)
+    }
+    return j.ended.Sub(j.Started)
+}
+
+// Progress returns the progress reported by the module instance; ok is false when
+// the module does not report progress.
+func (j *Job) Progress() (status progress.Status, ok bool) {
+    p, ok := j.instance.(progress.Progresser)
+    if !ok {
+        return progress.Status{}, false
+    }
+    return p.Progress(), true
+}
+
+// Results returns the rows of the job: while it is running, those streamed so far.
+func (j *Job) Results() [][]string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if j.ended.IsZero() {
+        return append([][]string(nil), j.streamed...)
+    }
+    return j.results
+}
+
+// Streamed returns the rows emitted by the module from index from onwards.
+func (j *Job) Streamed(from int) [][]string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if from >= len(j.streamed) {
+        return nil
+    }
+    return append([][]string(nil), j.streamed[from:]...)
+}
+
+// Skipped returns the targets the module skipped because they are out of scope,
+// in the order they were first reported.
+func (j *Job) Skipped() []string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return append([]string(nil), j.skipped...)
+}
+
+// Done returns a channel closed when the job has ended.
+func (j *Job) Done() <-chan struct{} {
+    return j.done
+}
+
+// Ended reports whether the job has ended and its results have been collected.
+func (j *Job) Ended() bool {
+    select {
+    case <-j.done:
+        return true
+    default:
+        return false
+    }
+}
+
+// Wait blocks until the job has ended.
+func (j *Job) Wait() {
+    <-j.done
+}
+
+// Kill cancels the job context; the job ends as cancelled.
+func (j *Job) Kill() {
+    j.mu.Lock()
+    j.killed = true
+    j.mu.Unlock()
+    j.cancel()
+}
+
+// Manager starts jobs and keeps track of them. It is safe for concurrent use.
+type Manager struct {
+    mu   sync.Mutex
+    jobs map[int]*Job
+    next int
+}
+
+// NewManager creates an empty job manager.
+func NewManager() *Manager {
+    return &Manager{jobs: make(map[int]*Job), next: 1}
+}
+
+// Start runs module in a new goroutine and returns the job tracking it.
+func (m *Manager) Start(module modules.Module, hooks Hooks) *Job {
+    ctx, cancel := context.WithCancel(context.Background())
+
+    options := make(map[string]string)
+    for _, opt := range module.Options() {
+        options[opt["name"]] = opt["value"]
+    }
+
+    m.mu.Lock()
+    job := &Job{
+        ID:       m.next,
+        Module:   module.Prompt(),
+        Options:  options,
+        Started:  time.Now(),
+        RunID:    hooks.RunID,
+        instance: module,
+        cancel:   cancel,
+        done:     make(chan struct{}),
+        state:    Running,
+    }
+    m.jobs[job.ID] = job
+    m.next++
+    m.mu.Unlock()
+
+    // Rows emitted after the run has ended are dropped
+    emit := func(row []string) {
+        row = append([]string(nil), row...)
+        job.mu.Lock()
+        if !job.ended.IsZero() {
+            job.mu.Unlock()
+            return
+        }
+        job.streamed = append(job.streamed, row)
+        job.mu.Unlock()
+        if hooks.Row != nil {
+            hooks.Row(job, row)
+        }
+    }
+
+    seen := make(map[string]bool)
+    skip := func(target string) {
+        job.mu.Lock()
+        defer job.mu.Unlock()
+        if !seen[target] {
+            seen[target] = true
+            job.skipped = append(job.skipped, target)
+        }
+    }
+
+    runCtx := stream.WithEmitter(ctx, emit)
+    if hooks.Scope != nil {
+        runCtx = scope.WithScope(runCtx, hooks.Scope, skip)
+    }
+
+    go func() {
+        defer close(job.done)
+        defer cancel()
+
+        module.Start()
+        rows := module.Run(runCtx)
+        module.Stop()
+
+        job.mu.Lock()
+        job.ended = time.Now()
+        streamed := len(job.streamed) > 0
+        // A cancelled module may return less than it emitted
+        if len(rows) == 0 && streamed {
+            rows = job.streamed
+        }
+        job.results = rows
+        switch {
+        case job.killed || ctx.Err() != nil:
+            job.state = Cancelled
+        case modules.IsErrorResult(rows):
+            job.state = Failed
+            job.err = rows[0][len(rows[0])-1]
+        default:
+            job.state = Finished
+        }
+        replay := !streamed && job.state != Failed
+        job.mu.Unlock()
+
+        if replay && hooks.Row != nil {
+            for _, row := range rows {
+                hooks.Row(job, row)
+            }
+        }
+        if hooks.Finish != nil {
+            hooks.Finish(job)
+        }
+    }()
+
+    return job
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(id int) (*Job, bool) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    job, ok := m.jobs[id]
+    return job, ok
+}
+
+// List returns every job ordered by ID.
+func (m *Manager) List() []*Job {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    out := make([]*Job, 0, len(m.jobs))
+    for _, job := range m.jobs {
+        out = append(out, job)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+// Running returns the jobs of the named module, or of every module when name is
+// empty, that have not ended yet.
+func (m *Manager) Running(name string) []*Job {
+    var out []*Job
+    for _, job := range m.List() {
+        if !job.Ended() && (name == "" || job.Module == name) {
+            out = append(out, job)
+        }
+    }
+    return out
+}
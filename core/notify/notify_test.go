@@ -0,0 +1,230 @@
+package notify
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/czz/oblivion/utils/findings"
+)
+
+// testEvent returns a Finding event with one vulnerability per severity.
+func testEvent(severities ...string) Event {
+    var vulns []findings.Vulnerability
+    for _, sev := range severities {
+        vulns = append(vulns, findings.Vulnerability{Title: "Issue " + sev, Target: "https://example.com", Severity: sev})
+    }
+    return FindingEvent("Job 1 (fuzzer)", "fuzzer", vulns)
+}
+
+func TestHTTPSinks(t *testing.T) {
+    var body map[string]interface{}
+    var contentType string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        contentType = r.Header.Get("Content-Type")
+        body = nil
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decoding payload: %v", err)
+        }
+        if r.URL.Path == "/fail" {
+            http.Error(w, "bad token", http.StatusForbidden)
+        }
+    }))
+    defer srv.Close()
+
+    e := testEvent("high")
+    cases := []struct {
+        typ   string
+        field string
+        want  string
+    }{
+        {"webhook", "title", e.Title},
+        {"slack", "text", "*" + e.Title + "*\n" + e.Message},
+        {"mattermost", "text", "*" + e.Title + "*\n" + e.Message},
+        {"discord", "content", "**" + e.Title + "**\n" + e.Message},
+    }
+    for _, c := range cases {
+        sink := Sink{Name: c.typ, Type: c.typ, Target: srv.URL + "/hook"}
+        if err := sink.Send(context.Background(), e); err != nil {
+            t.Fatalf("%s: %v", c.typ, err)
+        }
+        if contentType != "application/json" {
+            t.Errorf("%s: Content-Type %q", c.typ, contentType)
+        }
+        if got, _ := body[c.field].(string); got != c.want {
+            t.Errorf("%s: %s = %q, want %q", c.typ, c.field, got, c.want)
+        }
+    }
+    if got, _ := body["username"].(string); got != "oblivion" {
+        t.Errorf("discord: username = %q", got)
+    }
+
+    sink := Sink{Name: "broken", Type: "webhook", Target: srv.URL + "/fail"}
+    err := sink.Send(context.Background(), e)
+    if err == nil || !strings.Contains(err.Error(), "403") {
+        t.Errorf("non-2xx status: got error %v", err)
+    }
+}
+
+func TestDiscordLimit(t *testing.T) {
+    var content string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var body map[string]string
+        json.NewDecoder(r.Body).Decode(&body)
+        content = body["content"]
+    }))
+    defer srv.Close()
+
+    sink := Sink{Name: "discord", Type: "discord", Target: srv.URL}
+    if err := sink.Send(context.Background(), Event{Title: "Long", Message: strings.Repeat("x", 3000)}); err != nil {
+        t.Fatal(err)
+    }
+    if len(content) != 2000 || !strings.HasSuffix(content, "...") {
+        t.Errorf("content of %d characters, want 2000 ending with ...", len(content))
+    }
+}
+
+// fakeSMTP accepts one message and returns its envelope and data.
+func fakeSMTP(t *testing.T) (addr string, received chan []string) {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    received = make(chan []string, 1)
+    go func() {
+        defer l.Close()
+        conn, err := l.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        conn.SetDeadline(time.Now().Add(5 * time.Second))
+        r := bufio.NewReader(conn)
+        reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+        var lines []string
+        reply("220 localhost ESMTP")
+        for {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                return
+            }
+            line = strings.TrimRight(line, "\r\n")
+            cmd := strings.ToUpper(line)
+            switch {
+            case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+                reply("250 localhost")
+            case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+                lines = append(lines, line)
+                reply("250 OK")
+            case cmd == "DATA":
+                reply("354 End data with <CR><LF>.<CR><LF>")
+                for {
+                    data, err := r.ReadString('\n')
+                    if err != nil {
+                        return
+                    }
+                    data = strings.TrimRight(data, "\r\n")
+                    if data == "." {
+                        break
+                    }
+                    lines = append(lines, data)
+                }
+                reply("250 OK")
+            case cmd == "QUIT":
+                reply("221 Bye")
+                received <- lines
+                return
+            default:
+                reply("250 OK")
+            }
+        }
+    }()
+    return l.Addr().String(), received
+}
+
+func TestEmailSink(t *testing.T) {
+    addr, received := fakeSMTP(t)
+    sink := Sink{
+        Name:   "mail",
+        Type:   "email",
+        Target: "smtp://" + addr + "?from=oblivion@example.com&to=a@example.com,b@example.com",
+    }
+    if err := sink.Validate(); err != nil {
+        t.Fatal(err)
+    }
+    e := Event{Kind: Failed, Title: "Job 2 failed\r\nBcc: victim@example.com", Message: "timeout", Time: time.Now()}
+    if err := sink.Send(context.Background(), e); err != nil {
+        t.Fatal(err)
+    }
+
+    var lines []string
+    select {
+    case lines = <-received:
+    case <-time.After(5 * time.Second):
+        t.Fatal("no message received")
+    }
+    msg := strings.Join(lines, "\n")
+    for _, want := range []string{
+        "MAIL FROM:<oblivion@example.com>",
+        "RCPT TO:<a@example.com>",
+        "RCPT TO:<b@example.com>",
+        "From: oblivion@example.com",
+        "To: a@example.com, b@example.com",
+        "Subject: [oblivion] Job 2 failed Bcc: victim@example.com",
+    } {
+        if !strings.Contains(msg, want) {
+            t.Errorf("message lacks %q:\n%s", want, msg)
+        }
+    }
+    for _, line := range lines {
+        if line == "" {
+            break // End of the headers
+        }
+        if strings.HasPrefix(line, "Bcc:") {
+            t.Errorf("title injected a header: %q", line)
+        }
+    }
+}
+
+func TestSinkFilter(t *testing.T) {
+    cases := []struct {
+        name     string
+        sink     Sink
+        event    Event
+        send     bool
+        findings int
+    }{
+        {"no threshold", Sink{}, testEvent("info", "high"), true, 2},
+        {"below threshold", Sink{Severity: "high"}, testEvent("low", "medium"), false, 0},
+        {"all above", Sink{Severity: "medium"}, testEvent("high", "critical"), true, 2},
+        {"partly above", Sink{Severity: "high"}, testEvent("low", "high", "critical"), true, 2},
+        {"info threshold", Sink{Severity: "info"}, testEvent("info"), true, 1},
+        {"threshold ignores other kinds", Sink{Severity: "critical"}, Event{Kind: Finished}, true, 0},
+        {"kind not taken", Sink{Events: []string{Failed}}, testEvent("critical"), false, 0},
+        {"kind taken", Sink{Events: []string{Finding}, Severity: "low"}, testEvent("low"), true, 1},
+    }
+    for _, c := range cases {
+        got, ok := c.sink.filter(c.event)
+        if ok != c.send {
+            t.Errorf("%s: send = %v, want %v", c.name, ok, c.send)
+            continue
+        }
+        if ok && len(got.Findings) != c.findings {
+            t.Errorf("%s: %d findings, want %d", c.name, len(got.Findings), c.findings)
+        }
+    }
+
+    // A filtered event is rebuilt with the kept findings only
+    got, _ := Sink{Severity: "high"}.filter(testEvent("low", "high"))
+    if got.Severity != "high" || got.Title != "Job 1 (fuzzer): 1 new vulnerabilities" || strings.Contains(got.Message, "Issue low") {
+        t.Errorf("filtered event: %+v", got)
+    }
+}
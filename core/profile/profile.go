@@ -0,0 +1,132 @@
+package profile
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// validName restricts profile names to safe file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Profile is a named set of option values of a module.
+type Profile struct {
+    Module  string            `json:"module"`  // Module prompt
+    Options map[string]string `json:"options"` // Option name -> raw value
+}
+
+// Root returns the directory containing the profiles of a module
+// (~/.oblivion/profiles/<module>).
+func Root(module string) (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion", "profiles", module), nil
+}
+
+// ValidateName checks that name can be used as a profile name.
+func ValidateName(name string) error {
+    if !validName.MatchString(name) || strings.HasPrefix(name, ".") {
+        return fmt.Errorf("invalid profile name %q (allowed: letters, digits, '_', '-', '.')", name)
+    }
+    return nil
+}
+
+// path returns the file of a profile after validating its name.
+func path(module, name string) (string, error) {
+    if err := ValidateName(name); err != nil {
+        return "", err
+    }
+    root, err := Root(module)
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(root, name+".json"), nil
+}
+
+// Save stores the option values of a module as profile name, replacing it if it exists.
+func Save(module, name string, options map[string]string) error {
+    file, err := path(module, name)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(Profile{Module: module, Options: options}, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp := file + ".tmp"
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, file)
+}
+
+// Load reads profile name of a module.
+func Load(module, name string) (*Profile, error) {
+    file, err := path(module, name)
+    if err != nil {
+        return nil, err
+    }
+    data, err := os.ReadFile(file)
+    if os.IsNotExist(err) {
+        return nil, fmt.Errorf("profile %s does not exist for %s", name, module)
+    }
+    if err != nil {
+        return nil, err
+    }
+    p := &Profile{}
+    if err := json.Unmarshal(data, p); err != nil {
+        return nil, fmt.Errorf("profile %s: %v", name, err)
+    }
+    if p.Options == nil {
+        p.Options = make(map[string]string)
+    }
+    return p, nil
+}
+
+// List returns the names of the profiles of a module, sorted alphabetically.
+func List(module string) ([]string, error) {
+    root, err := Root(module)
+    if err != nil {
+        return nil, err
+    }
+    entries, err := os.ReadDir(root)
+    if os.IsNotExist(err) {
+        return []string{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    names := []string{}
+    for _, e := range entries {
+        name := strings.TrimSuffix(e.Name(), ".json")
+        if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") && ValidateName(name) == nil {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+// Delete removes profile name of a module.
+func Delete(module, name string) error {
+    file, err := path(module, name)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(file); os.IsNotExist(err) {
+        return fmt.Errorf("profile %s does not exist for %s", name, module)
+    } else if err != nil {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,291 @@
+package report
+
+import (
+    "bytes"
+    "embed"
+    "fmt"
+    htmltemplate "html/template"
+    "io"
+    "net"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    texttemplate "text/template"
+    "time"
+
+    "github.com/czz/oblivion/utils/export"
+    "github.com/czz/oblivion/utils/findings"
+    "github.com/czz/oblivion/utils/results"
+)
+
+// Reports are rendered from templates/report.html and templates/report.md, which can
+// be replaced by files with the same name in ~/.oblivion/templates. The sarif and jsonl
+// formats write only the vulnerabilities, for CI pipelines.
+
+//go:embed templates/*
+var builtin embed.FS
+
+// Formats lists the supported report formats.
+var Formats = []string{"html", "md", "sarif", "jsonl"}
+
+// templateFormats lists the formats rendered from templates.
+var templateFormats = []string{"html", "md"}
+
+// Report is the data available to the templates.
+type Report struct {
+    Title      string                   // Report title
+    Workspace  string                   // Workspace the results come from
+    Generated  time.Time                // When the report was generated
+    Scope      []string                 // Scope entries, exclusions prefixed with '!'
+    Summary    Summary                  // Counts of everything found
+    Hosts      []HostReport             // Findings grouped by host
+    Subdomains []findings.Subdomain     // Every subdomain
+    Services   []findings.Service       // Every open port
+    URLs       []findings.URL           // Every crawled or fuzzed URL
+    Vulns      []findings.Vulnerability // Every vulnerability, most severe first
+    Modules    []ModuleResults          // Result table of every module with results
+}
+
+// Summary counts the findings of a report.
+type Summary struct {
+    Hosts      int
+    Subdomains int
+    Services   int
+    URLs       int
+    Vulns      int
+    Severities []SeverityCount // Vulnerabilities per severity, most severe first
+}
+
+// SeverityCount is the number of vulnerabilities of a severity.
+type SeverityCount struct {
+    Severity string
+    Count    int
+}
+
+// HostReport groups what was found on a single host.
+type HostReport struct {
+    Host     string
+    Services []findings.Service
+    URLs     []findings.URL
+    Vulns    []findings.Vulnerability
+}
+
+// ModuleResults is the result table of a module.
+type ModuleResults struct {
+    Name    string
+    Headers []string
+    Rows    [][]string
+}
+
+// Build gathers the findings of the session and the results of its modules.
+func Build(title, workspace string, scope []string, store *findings.Store, mods []export.Data) *Report {
+    r := &Report{
+        Title:      title,
+        Workspace:  workspace,
+        Generated:  time.Now(),
+        Scope:      scope,
+        Subdomains: store.Subdomains(),
+        Services:   store.Services(),
+        URLs:       store.URLs(),
+        Vulns:      store.Vulnerabilities(),
+    }
+
+    hosts := make(map[string]*HostReport)
+    host := func(name string) *HostReport {
+        name = strings.ToLower(strings.TrimSuffix(name, "."))
+        h, ok := hosts[name]
+        if !ok {
+            h = &HostReport{Host: name}
+            hosts[name] = h
+        }
+        return h
+    }
+    for _, h := range store.Hosts() {
+        host(h.Address)
+    }
+    for _, d := range r.Subdomains {
+        host(d.Name)
+    }
+    for _, svc := range r.Services {
+        h := host(svc.Host)
+        h.Services = append(h.Services, svc)
+    }
+    for _, u := range r.URLs {
+        h := host(hostOf(u.URL))
+        h.URLs = append(h.URLs, u)
+    }
+    for _, v := range r.Vulns {
+        h := host(hostOf(v.Target))
+        h.Vulns = append(h.Vulns, v)
+    }
+    for _, h := range hosts {
+        if h.Host != "" {
+            r.Hosts = append(r.Hosts, *h)
+        }
+    }
+    // Hosts with vulnerabilities first, then by name
+    sort.Slice(r.Hosts, func(i, j int) bool {
+        vi, vj := len(r.Hosts[i].Vulns) > 0, len(r.Hosts[j].Vulns) > 0
+        if vi != vj {
+            return vi
+        }
+        return r.Hosts[i].Host < r.Hosts[j].Host
+    })
+
+    for _, m := range mods {
+        if len(m.Rows) > 0 {
+            r.Modules = append(r.Modules, ModuleResults{Name: m.Name, Headers: results.Complete(m.Headers, m.Rows), Rows: m.Rows})
+        }
+    }
+
+    counts := make(map[string]int)
+    for _, v := range r.Vulns {
+        counts[strings.ToLower(v.Severity)]++
+    }
+    for _, sev := range []string{"critical", "high", "medium", "low", "info"} {
+        if counts[sev] > 0 {
+            r.Summary.Severities = append(r.Summary.Severities, SeverityCount{Severity: sev, Count: counts[sev]})
+        }
+    }
+    r.Summary.Hosts = len(r.Hosts)
+    r.Summary.Subdomains = len(r.Subdomains)
+    r.Summary.Services = len(r.Services)
+    r.Summary.URLs = len(r.URLs)
+    r.Summary.Vulns = len(r.Vulns)
+    return r
+}
+
+// hostOf returns the host of a URL, host:port or host.
+func hostOf(target string) string {
+    if strings.Contains(target, "://") {
+        if u, err := url.Parse(target); err == nil {
+            return u.Hostname()
+        }
+    }
+    if h, _, err := net.SplitHostPort(target); err == nil {
+        return h
+    }
+    return target
+}
+
+// FormatOf returns the report format matching the extension of a file name.
+func FormatOf(path string) (string, bool) {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".html", ".htm":
+        return "html", true
+    case ".md", ".markdown":
+        return "md", true
+    case ".sarif":
+        return "sarif", true
+    case ".jsonl":
+        return "jsonl", true
+    }
+    return "", false
+}
+
+// TemplatesDir returns the directory of the user templates (~/.oblivion/templates).
+func TemplatesDir() (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion", "templates"), nil
+}
+
+// templateSource returns the template of a format and where it comes from: the user
+// template when there is one, the built-in one otherwise.
+func templateSource(format string) (string, string, error) {
+    name := "report." + format
+    if dir, err := TemplatesDir(); err == nil {
+        path := filepath.Join(dir, name)
+        if data, err := os.ReadFile(path); err == nil {
+            return string(data), path, nil
+        } else if !os.IsNotExist(err) {
+            return "", "", err
+        }
+    }
+    data, err := builtin.ReadFile("templates/" + name)
+    if err != nil {
+        return "", "", fmt.Errorf("unknown report format %s (available: %s)", format, strings.Join(Formats, ", "))
+    }
+    return string(data), "built-in", nil
+}
+
+// funcs are the helper functions available to the templates.
+var funcs = map[string]interface{}{
+    "upper": strings.ToUpper,
+    "lower": strings.ToLower,
+    "join":  strings.Join,
+    "date":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+    // md escapes the characters breaking a markdown table cell
+    "md": strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ").Replace,
+}
+
+// Render writes the report in the given format and returns the template used, if
+// any. HTML templates escape their values automatically.
+func Render(w io.Writer, format string, r *Report) (string, error) {
+    switch format {
+    case "sarif":
+        return "", findings.WriteSARIF(w, r.Vulns)
+    case "jsonl":
+        return "", findings.WriteJSONL(w, r.Vulns)
+    }
+
+    src, origin, err := templateSource(format)
+    if err != nil {
+        return "", err
+    }
+    if format == "html" {
+        t, err := htmltemplate.New("report").Funcs(funcs).Parse(src)
+        if err != nil {
+            return origin, fmt.Errorf("template %s: %v", origin, err)
+        }
+        return origin, t.Execute(w, r)
+    }
+    t, err := texttemplate.New("report").Funcs(funcs).Parse(src)
+    if err != nil {
+        return origin, fmt.Errorf("template %s: %v", origin, err)
+    }
+    return origin, t.Execute(w, r)
+}
+
+// Save renders the report to a file and returns the template used. Nothing is
+// written when the template fails.
+func Save(path, format string, r *Report) (string, error) {
+    var buf bytes.Buffer
+    origin, err := Render(&buf, format, r)
+    if err != nil {
+        return origin, err
+    }
+    return origin, os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// WriteTemplates copies the built-in templates to the user templates directory so
+// they can be customized; existing files are kept. It returns the files written.
+func WriteTemplates() ([]string, error) {
+    dir, err := TemplatesDir()
+    if err != nil {
+        return nil, err
+    }
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+    var written []string
+    for _, format := range templateFormats {
+        path := filepath.Join(dir, "report."+format)
+        if _, err := os.Stat(path); err == nil {
+            continue
+        }
+        data, err := builtin.ReadFile("templates/report." + format)
+        if err != nil {
+            return written, err
+        }
+        if err := os.WriteFile(path, data, 0644); err != nil {
+            return written, err
+        }
+        written = append(written, path)
+    }
+    return written, nil
+}
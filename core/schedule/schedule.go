@@ -0,0 +1,266 @@
+package schedule
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Kinds of scheduled runs.
+const (
+    KindModule   = "module"
+    KindWorkflow = "workflow"
+)
+
+// MinInterval is the shortest interval accepted between two runs.
+const MinInterval = time.Minute
+
+// Schedule re-runs a module with a snapshot of its options, or a workflow file,
+// at a fixed interval.
+type Schedule struct {
+    ID      int               `json:"id"`
+    Kind    string            `json:"kind"`              // KindModule or KindWorkflow
+    Target  string            `json:"target"`            // Module prompt or workflow file
+    Every   string            `json:"every"`             // Interval, e.g. 6h or 1d
+    Options map[string]string `json:"options,omitempty"` // Option name -> raw value, for modules
+    Created time.Time         `json:"created"`
+    LastRun time.Time         `json:"last_run"` // Start of the last run, zero before the first one
+
+    // Runs maps "<workspace>/<step>" to the ID of the latest stored run, compared with
+    // the next one. The step of a module schedule is the module prompt.
+    Runs map[string]string `json:"runs,omitempty"`
+}
+
+// Interval returns the time between two runs.
+func (s Schedule) Interval() time.Duration {
+    d, _ := ParseInterval(s.Every)
+    return d
+}
+
+// Next returns when the schedule runs next: right away before the first run, one
+// interval after the start of the last run otherwise.
+func (s Schedule) Next() time.Time {
+    if s.LastRun.IsZero() {
+        return s.Created
+    }
+    return s.LastRun.Add(s.Interval())
+}
+
+// ParseInterval parses a Go duration (30m, 6h) or a number of days (1d, 7d).
+func ParseInterval(value string) (time.Duration, error) {
+    var d time.Duration
+    var err error
+    if days, ok := strings.CutSuffix(value, "d"); ok {
+        var n int
+        n, err = strconv.Atoi(days)
+        d = time.Duration(n) * 24 * time.Hour
+    } else {
+        d, err = time.ParseDuration(value)
+    }
+    if err != nil {
+        return 0, fmt.Errorf("invalid interval %q (e.g. 30m, 6h, 1d)", value)
+    }
+    if d < MinInterval {
+        return 0, fmt.Errorf("interval %s is shorter than %s", value, MinInterval)
+    }
+    return d, nil
+}
+
+// Path returns the file storing the schedules (~/.oblivion/schedules.json).
+func Path() (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion", "schedules.json"), nil
+}
+
+// Scheduler keeps the schedules, persists them and starts the due ones.
+type Scheduler struct {
+    mu        sync.Mutex
+    path      string
+    schedules []*Schedule
+    running   map[int]bool
+    stop      chan struct{}
+}
+
+// Open loads the schedules stored in Path. A missing file means no schedules.
+func Open() (*Scheduler, error) {
+    path, err := Path()
+    if err != nil {
+        return nil, err
+    }
+    sc := &Scheduler{path: path, running: make(map[int]bool)}
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return sc, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, &sc.schedules); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    return sc, nil
+}
+
+// save writes the schedules; the caller holds mu.
+func (sc *Scheduler) save() error {
+    if err := os.MkdirAll(filepath.Dir(sc.path), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(sc.schedules, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp := sc.path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, sc.path)
+}
+
+// Add stores a new schedule and returns it with its ID.
+func (sc *Scheduler) Add(kind, target, every string, options map[string]string) (Schedule, error) {
+    if _, err := ParseInterval(every); err != nil {
+        return Schedule{}, err
+    }
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+
+    id := 1
+    for _, s := range sc.schedules {
+        if s.ID >= id {
+            id = s.ID + 1
+        }
+    }
+    s := &Schedule{ID: id, Kind: kind, Target: target, Every: every, Options: options, Created: time.Now()}
+    sc.schedules = append(sc.schedules, s)
+    if err := sc.save(); err != nil {
+        sc.schedules = sc.schedules[:len(sc.schedules)-1]
+        return Schedule{}, err
+    }
+    return *s, nil
+}
+
+// Remove deletes a schedule. A run in progress is not interrupted.
+func (sc *Scheduler) Remove(id int) error {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    for i, s := range sc.schedules {
+        if s.ID == id {
+            sc.schedules = append(sc.schedules[:i], sc.schedules[i+1:]...)
+            return sc.save()
+        }
+    }
+    return fmt.Errorf("schedule %d not found", id)
+}
+
+// List returns a copy of every schedule, by ID.
+func (sc *Scheduler) List() []Schedule {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    out := make([]Schedule, 0, len(sc.schedules))
+    for _, s := range sc.schedules {
+        out = append(out, *s)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+// Running reports whether a run of the schedule is in progress.
+func (sc *Scheduler) Running(id int) bool {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    return sc.running[id]
+}
+
+// Finish records the end of a run started by the scheduler, with the IDs of the
+// runs it stored by "<workspace>/<step>".
+func (sc *Scheduler) Finish(id int, runs map[string]string) error {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    delete(sc.running, id)
+    for _, s := range sc.schedules {
+        if s.ID != id {
+            continue
+        }
+        if s.Runs == nil {
+            s.Runs = make(map[string]string)
+        }
+        for k, v := range runs {
+            s.Runs[k] = v
+        }
+        return sc.save()
+    }
+    return nil
+}
+
+// due marks the schedules due at now as running and returns them. A schedule still
+// running from its previous start is not started again.
+func (sc *Scheduler) due(now time.Time) ([]Schedule, error) {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    var out []Schedule
+    for _, s := range sc.schedules {
+        if sc.running[s.ID] || now.Before(s.Next()) {
+            continue
+        }
+        s.LastRun = now
+        sc.running[s.ID] = true
+        out = append(out, *s)
+    }
+    if len(out) == 0 {
+        return nil, nil
+    }
+    return out, sc.save()
+}
+
+// Start checks the schedules every tick and calls run for each due one, in its own
+// goroutine. run must call Finish when the run is over. onError receives the errors
+// saving the schedules, if not nil.
+func (sc *Scheduler) Start(tick time.Duration, run func(Schedule), onError func(error)) {
+    sc.mu.Lock()
+    if sc.stop != nil {
+        sc.mu.Unlock()
+        return
+    }
+    stop := make(chan struct{})
+    sc.stop = stop
+    sc.mu.Unlock()
+
+    go func() {
+        ticker := time.NewTicker(tick)
+        defer ticker.Stop()
+        for {
+            due, err := sc.due(time.Now())
+            if err != nil && onError != nil {
+                onError(err)
+            }
+            for _, s := range due {
+                go run(s)
+            }
+            select {
+            case <-ticker.C:
+            case <-stop:
+                return
+            }
+        }
+    }()
+}
+
+// Stop stops checking the schedules. Runs in progress are not interrupted.
+func (sc *Scheduler) Stop() {
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+    if sc.stop != nil {
+        close(sc.stop)
+        sc.stop = nil
+    }
+}
@@ -0,0 +1,333 @@
+package secret
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Secrets are stored in ~/.oblivion/secrets.json, each value encrypted with AES-256-GCM.
+// Options reference them as ${SECRET:name}.
+//
+// The key is taken from $OBLIVION_SECRET_KEY (32 bytes, hex or base64 encoded) when
+// it is set, from ~/.oblivion/secret.key otherwise; the key file is created with the
+// first secret. A key file next to the store only protects the values from being read
+// by accident, such as in a backup of secrets.json or a screen share: anyone able to
+// read ~/.oblivion can read the key too. Keep the key in the environment (e.g. from a
+// password manager) to protect the store at rest.
+
+// KeyEnv is the environment variable holding the store key.
+const KeyEnv = "OBLIVION_SECRET_KEY"
+
+// validName restricts secret names to the characters accepted in ${SECRET:name}.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// mu serializes access to the store file.
+var mu sync.Mutex
+
+// Dir returns the directory containing the store and its key (~/.oblivion).
+func Dir() (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion"), nil
+}
+
+// ValidateName checks that name can be used as a secret name.
+func ValidateName(name string) error {
+    if !validName.MatchString(name) {
+        return fmt.Errorf("invalid secret name %q (allowed: letters, digits, '_', '-', '.')", name)
+    }
+    return nil
+}
+
+// Set encrypts and stores a secret, replacing it if it exists.
+func Set(name, value string) error {
+    if err := ValidateName(name); err != nil {
+        return err
+    }
+    mu.Lock()
+    defer mu.Unlock()
+
+    store, err := load()
+    if err != nil {
+        return err
+    }
+    // A new key would orphan the stored secrets, so it is only created for an empty store
+    gcm, err := cipherFor(len(store) == 0)
+    if err != nil {
+        return err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+        return err
+    }
+    // The name is authenticated so that values cannot be swapped between secrets
+    sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+    store[name] = base64.StdEncoding.EncodeToString(sealed)
+    masked.reset()
+    return save(store)
+}
+
+// Get decrypts a secret.
+func Get(name string) (string, error) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    store, err := load()
+    if err != nil {
+        return "", err
+    }
+    enc, ok := store[name]
+    if !ok {
+        return "", fmt.Errorf("secret %s does not exist", name)
+    }
+    gcm, err := cipherFor(false)
+    if err != nil {
+        return "", err
+    }
+    return open(gcm, name, enc)
+}
+
+// List returns the names of the stored secrets, sorted alphabetically.
+func List() ([]string, error) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    store, err := load()
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(store))
+    for name := range store {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+// Delete removes a secret.
+func Delete(name string) error {
+    mu.Lock()
+    defer mu.Unlock()
+
+    store, err := load()
+    if err != nil {
+        return err
+    }
+    if _, ok := store[name]; !ok {
+        return fmt.Errorf("secret %s does not exist", name)
+    }
+    delete(store, name)
+    masked.reset()
+    return save(store)
+}
+
+// Values returns the decrypted value of every secret, used to mask them in output,
+// history and logs. Secrets that cannot be decrypted are skipped.
+func Values() []string {
+    mu.Lock()
+    defer mu.Unlock()
+    return values()
+}
+
+// values decrypts every secret; mu must be held.
+func values() []string {
+    store, err := load()
+    if err != nil || len(store) == 0 {
+        return nil
+    }
+    gcm, err := cipherFor(false)
+    if err != nil {
+        return nil
+    }
+    var out []string
+    for name, enc := range store {
+        if v, err := open(gcm, name, enc); err == nil && v != "" {
+            out = append(out, v)
+        }
+    }
+    return out
+}
+
+// minMasked is the length under which secret values are not masked, as they would
+// hide unrelated text.
+const minMasked = 4
+
+// maskCache keeps the values Mask replaces, so that the store is not decrypted for
+// every line printed. It is reset by Set and Delete, and when the store file changes,
+// e.g. by an oblivion run in another terminal.
+type maskCache struct {
+    valid   bool
+    modTime time.Time
+    size    int64
+    values  []string // Longest first
+}
+
+// masked is guarded by mu.
+var masked maskCache
+
+// reset drops the cached values; mu must be held.
+func (c *maskCache) reset() {
+    *c = maskCache{}
+}
+
+// get returns the values to mask, decrypting the store only when it changed; mu
+// must be held.
+func (c *maskCache) get() []string {
+    var modTime time.Time
+    var size int64
+    if dir, err := Dir(); err == nil {
+        if info, err := os.Stat(filepath.Join(dir, "secrets.json")); err == nil {
+            modTime, size = info.ModTime(), info.Size()
+        }
+    }
+    if c.valid && modTime.Equal(c.modTime) && size == c.size {
+        return c.values
+    }
+
+    var vals []string
+    for _, v := range values() {
+        if len(v) >= minMasked {
+            vals = append(vals, v)
+        }
+    }
+    // Longest first, so that a secret containing another one is masked whole
+    sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+    *c = maskCache{valid: true, modTime: modTime, size: size, values: vals}
+    return vals
+}
+
+// Mask replaces every secret value contained in text with "****".
+func Mask(text string) string {
+    mu.Lock()
+    vals := masked.get()
+    mu.Unlock()
+    for _, v := range vals {
+        text = strings.ReplaceAll(text, v, "****")
+    }
+    return text
+}
+
+// open decrypts a stored value.
+func open(gcm cipher.AEAD, name, enc string) (string, error) {
+    sealed, err := base64.StdEncoding.DecodeString(enc)
+    if err != nil || len(sealed) < gcm.NonceSize() {
+        return "", fmt.Errorf("secret %s is corrupted", name)
+    }
+    nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+    plain, err := gcm.Open(nil, nonce, data, []byte(name))
+    if err != nil {
+        return "", fmt.Errorf("secret %s cannot be decrypted (wrong key?)", name)
+    }
+    return string(plain), nil
+}
+
+// cipherFor returns the AES-GCM cipher of the store key: the key in $OBLIVION_SECRET_KEY,
+// or the key file, created when create is true and it does not exist yet.
+func cipherFor(create bool) (cipher.AEAD, error) {
+    key, err := storeKey(create)
+    if err != nil {
+        return nil, err
+    }
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+// storeKey returns the 256 bit store key.
+func storeKey(create bool) ([]byte, error) {
+    if env := strings.TrimSpace(os.Getenv(KeyEnv)); env != "" {
+        key, err := hex.DecodeString(env)
+        if err != nil {
+            key, err = base64.StdEncoding.DecodeString(env)
+        }
+        if err != nil || len(key) != 32 {
+            return nil, fmt.Errorf("%s must hold a 256 bit key, hex or base64 encoded", KeyEnv)
+        }
+        return key, nil
+    }
+
+    dir, err := Dir()
+    if err != nil {
+        return nil, err
+    }
+    keyFile := filepath.Join(dir, "secret.key")
+    key, err := os.ReadFile(keyFile)
+    if os.IsNotExist(err) && create {
+        key = make([]byte, 32)
+        if _, err := io.ReadFull(rand.Reader, key); err != nil {
+            return nil, err
+        }
+        if err := os.MkdirAll(dir, 0755); err != nil {
+            return nil, err
+        }
+        if err := os.WriteFile(keyFile, key, 0600); err != nil {
+            return nil, err
+        }
+    } else if os.IsNotExist(err) {
+        return nil, fmt.Errorf("key file %s not found, the stored secrets cannot be decrypted without it (or set %s)", keyFile, KeyEnv)
+    } else if err != nil {
+        return nil, err
+    }
+    if len(key) != 32 {
+        return nil, fmt.Errorf("key file %s is not a 256 bit key", keyFile)
+    }
+    return key, nil
+}
+
+// load reads the encrypted store; a missing file yields an empty store.
+func load() (map[string]string, error) {
+    store := make(map[string]string)
+    dir, err := Dir()
+    if err != nil {
+        return nil, err
+    }
+    data, err := os.ReadFile(filepath.Join(dir, "secrets.json"))
+    if os.IsNotExist(err) {
+        return store, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, &store); err != nil {
+        return nil, fmt.Errorf("secrets store: %v", err)
+    }
+    return store, nil
+}
+
+// save writes the encrypted store.
+func save(store map[string]string) error {
+    dir, err := Dir()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(store, "", "  ")
+    if err != nil {
+        return err
+    }
+    file := filepath.Join(dir, "secrets.json")
+    tmp := file + ".tmp"
+    if err := os.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, file)
+}
@@ -0,0 +1,60 @@
+package secret
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestKeyFile(t *testing.T) {
+    home := t.TempDir()
+    t.Setenv("HOME", home)
+    t.Setenv(KeyEnv, "")
+
+    if err := Set("token", "s3cr3t-value"); err != nil {
+        t.Fatal(err)
+    }
+    if got := Mask("Bearer s3cr3t-value"); got != "Bearer ****" {
+        t.Errorf("Mask = %q", got)
+    }
+    if err := Set("cookie", "session=abcdef"); err != nil {
+        t.Fatal(err)
+    }
+    if got := Mask("session=abcdef"); got != "****" {
+        t.Errorf("Mask after Set = %q", got)
+    }
+
+    // Losing the key must not silently create another one
+    os.Remove(filepath.Join(home, ".oblivion", "secret.key"))
+    if err := Set("other", "value"); err == nil || !strings.Contains(err.Error(), "not found") {
+        t.Errorf("Set without the key of a non empty store: %v", err)
+    }
+    if err := Delete("token"); err != nil {
+        t.Fatal(err)
+    }
+    if got := Mask("s3cr3t-value"); got != "s3cr3t-value" {
+        t.Errorf("Mask after Delete = %q", got)
+    }
+}
+
+func TestKeyEnv(t *testing.T) {
+    home := t.TempDir()
+    t.Setenv("HOME", home)
+    t.Setenv(KeyEnv, strings.Repeat("ab", 32))
+
+    if err := Set("token", "from-env"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := os.Stat(filepath.Join(home, ".oblivion", "secret.key")); !os.IsNotExist(err) {
+        t.Errorf("key file created with %s set", KeyEnv)
+    }
+    if v, err := Get("token"); err != nil || v != "from-env" {
+        t.Errorf("Get = %q, %v", v, err)
+    }
+
+    t.Setenv(KeyEnv, "short")
+    if _, err := Get("token"); err == nil {
+        t.Errorf("Get with an invalid %s succeeded", KeyEnv)
+    }
+}
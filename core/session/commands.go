@@ -4,12 +4,15 @@ import (
     "fmt"
     "os"
     "strings"
-    "context"
-    "os/signal"
-    "syscall"
 
+    "github.com/czz/oblivion/core/jobs"
+    "github.com/czz/oblivion/core/secret"
     "github.com/czz/oblivion/core/tui"
     "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/export"
+    "github.com/czz/oblivion/utils/help"
+    "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/results"
 )
 
 // registerCommands initializes the command map with available command handlers.
@@ -21,22 +24,76 @@ func (s *Session) registerCommands() {
         "use":     s.handleUse,
         "options": s.handleOptions,
         "set":     s.handleSet,
+        "unset":   s.handleUnset,
+        "reset":   s.handleReset,
+        "profile": s.handleProfile,
         "run":     s.handleRun,
+        "check":   s.handleCheck,
         "stop":    s.handleStop,
         "show":    s.handleShow,
         "save":    s.handleSave,
         "back":    s.handleBack,
+        "resource": s.handleResource,
+        "sleep":   s.handleSleep,
+        "wait":    s.handleWait,
+        "workspace": s.handleWorkspace,
+        "scope":   s.handleScope,
+        "secret":  s.handleSecret,
+        "hosts":   s.findingsHandler("hosts"),
+        "subdomains": s.findingsHandler("subdomains"),
+        "services": s.findingsHandler("services"),
+        "urls":    s.findingsHandler("urls"),
+        "vulns":   s.findingsHandler("vulns"),
+        "report":  s.handleReport,
+        "diff":    s.handleDiff,
+        "schedule": s.handleSchedule,
+        "notify":   s.handleNotify,
+        "workflow": s.handleWorkflow,
+        "jobs":    s.handleJobs,
+        "setg":    s.handleSetg,
+        "unsetg":  s.handleUnsetg,
+        "showg":   s.handleShowg,
     }
 }
 
-// handleHelp displays help for core and module commands.
+// handleHelp displays help for core and module commands, or for a single option
+// of the active module.
 func (s *Session) handleHelp(args []string) {
+    if len(args) == 1 {
+        s.optionHelp(args[0])
+        return
+    }
+
     coreHelp := [][]string{
         {"Core Commands", ""},
         {"=============", ""},
         {"  Command", "Description"},
         {"  -------", "-----------"},
-        {"  help", "Help Menu"},
+        {"  help [option]", "Help Menu, or the details of an option of the selected module"},
+        {"  resource <file>", "Executes the commands contained in a resource script"},
+        {"  sleep <seconds>", "Pauses execution (useful in resource scripts)"},
+        {"  wait [module_name]", "Waits for background modules to finish"},
+        {"  jobs [kill|wait|show <id>]", "Lists module runs or kills, waits for or shows one of them"},
+        {"  setg <option> <value>", "Sets a global value inherited by every module option with that name"},
+        {"  unsetg <option>", "Removes a global value"},
+        {"  showg", "Lists global values"},
+        {"  scope [show|add|remove|load|clear] [entry ...]", "Restricts the targets modules may contact; '!entry' excludes (e.g. scope add *.example.com !admin.example.com)"},
+        {"  secret list|set|delete [name] [value]", "Manages encrypted secrets used in option values as ${SECRET:name} (the value is prompted when omitted)"},
+        {"  workspace [list|create|use|delete] [name]", "Manages workspaces persisting options and results"},
+        {"  workflow run|validate <file>", "Runs a YAML workflow chaining several modules"},
+        {"", ""},
+        {"Findings Commands", ""},
+        {"=============", ""},
+        {"  Command", "Description"},
+        {"  -------", "-----------"},
+        {"  hosts [filter ...] [--sort col ...]", "Lists hosts discovered by all modules"},
+        {"  subdomains [filter ...] [--sort col ...]", "Lists discovered subdomains"},
+        {"  services [filter ...] [--sort col ...]", "Lists open ports and banners"},
+        {"  urls [filter ...] [--sort col ...]", "Lists crawled and fuzzed URLs"},
+        {"  vulns [filter ...] [--sort col ...]", "Lists reported vulnerabilities"},
+        {"", "Takes the flags of show; 'column=regex' and a plain term are short for --filter and --grep, e.g. services port=443"},
+        {"  report <file> [--format html|md|sarif|jsonl] [--title t]", "Writes an HTML or Markdown report of the findings and the results of every module, or the vulnerabilities as SARIF or JSON lines"},
+        {"  report templates", "Copies the report templates to ~/.oblivion/templates to customize them"},
         {"", ""},
         {"Module Commands", ""},
         {"=============", ""},
@@ -46,10 +103,22 @@ func (s *Session) handleHelp(args []string) {
         {"  use <module>", "Selects a module to use"},
         {"  options", "Displays available options for the selected module"},
         {"  set <option> <value>", "Sets a value for a module option"},
+        {"", "@module[.results[.N]] uses column N (default 0) of another module's results"},
+        {"", "${ENV:NAME} and ${SECRET:name} are replaced by an environment variable or a secret when the module runs"},
+        {"  unset <option>", "Restores the default (or global) value of a module option"},
+        {"  reset", "Restores the default (or global) value of every option of the module"},
+        {"  profile list|save|load|delete [name]", "Manages named sets of option values of the module (~/.oblivion/profiles)"},
+        {"  check", "Checks that required options are set and every value is valid"},
         {"  run [&]","Executes the selected module in foreground (wait, crtl-c to stop) or background (no wait)"},
-        {"  stop [module_name]","stop the selected module in background"},
-        {"  show [module_name]", "Show results of a module. Module name is optional when inside a module."},
-        {"  save <filename>", "Saves the module output to the specified file"},
+        {"  stop [module_name]","Stops every background job of the module"},
+        {"  show [module_name] [flags]", "Show results of a module. Module name is optional when inside a module."},
+        {"", "--sort col [--desc], --filter col=regex, --grep regex, --limit n, --offset n, --columns a,b, --pager"},
+        {"  diff <module> [runA [runB]] [--format table|json]", "Lists the stored runs of a module, or the rows added, removed and changed from runA to runB (default latest); runs are IDs, prefixes, latest or previous"},
+        {"  notify [list|remove <name>|test [name]]", "Lists, removes or tests the notification sinks"},
+        {"  notify add <name> <type> <url|command> [--on events] [--severity level]", "Sends job ends, failures, new vulnerabilities and schedule changes to a webhook, slack, mattermost, discord, email (smtp://) or exec sink"},
+        {"  schedule [list|remove <id>]", "Lists or removes the scheduled runs"},
+        {"  schedule add <module|workflow.yml> every <interval>", "Re-runs a module with its current options, or a workflow, every interval (30m, 6h, 1d) while oblivion is open, notifying new results"},
+        {"  save <filename> [--format f]", "Saves the module output to the specified file, in the module's own format or in json, jsonl, csv, tsv, txt, md, html, xml, sarif or findings"},
         {"  back", "Returns to core (exit module)"},
     }
 
@@ -57,8 +126,30 @@ func (s *Session) handleHelp(args []string) {
 
     if s.isModuleActive() {
         module := *s.activeModule
-        fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, module.Help()))
+        fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1, MaxWidth: s.terminalWidth / 3}, module.Help()))
+        if _, ok := module.(modules.Configurable); ok {
+            fmt.Println("Type 'help <option>' for the details of an option.")
+        }
+    }
+}
+
+// optionHelp displays the type, syntax, default and details of an option of the
+// active module.
+func (s *Session) optionHelp(name string) {
+    if !s.isModuleActive() {
+        fmt.Println(s.Tui.Red("No active module."))
+        return
+    }
+    om, ok := s.optionManager(*s.activeModule)
+    if !ok {
+        return
+    }
+    opt, ok := om.Get(name)
+    if !ok {
+        fmt.Println(s.Tui.Red("Unknown option: " + name))
+        return
     }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1, MaxWidth: s.terminalWidth / 2}, help.Option(opt)))
 }
 
 // handleSearch searches modules based on name, author, or description.
@@ -130,10 +221,15 @@ func (s *Session) handleOptions(args []string) {
     }
 
     for _, opt := range module.Options() {
-        val := opt["value"]
+        val := secret.Mask(opt["value"])
         if val == "<nil>" {
             val = ""
         }
+        if opt["global"] == "true" {
+            val += " (global)"
+        } else if opt["changed"] == "true" {
+            val += " (changed)"
+        }
         line := []string{"  " + opt["name"], val, opt["required"], opt["description"]}
         optionsTable = append(optionsTable, line)
     }
@@ -155,75 +251,141 @@ func (s *Session) handleSet(args []string) {
     key := args[0]
     value := strings.Join(args[1:], " ")
     module := *s.activeModule
+
+    // Resolve @module references to the results of other modules
+    value, err := (*s.Modules).ResolveRefs(value)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+
     result := module.Set(key, value)
 
+    if len(result) == 2 && result[0] == "Error" {
+        fmt.Println(s.Tui.Red("Error: " + result[1]))
+        return
+    }
     if len(result) == 2 {
-        fmt.Println(s.Tui.Yellow(result[0] + " => " + result[1]))
+        fmt.Println(s.Tui.Yellow(result[0] + " => " + secret.Mask(result[1])))
+        // result[0] is the registered name, whatever the case typed
+        s.recordOption(module.Prompt(), result[0], value)
     }
 }
 
-// handleRun executes the active module and prints the results.
-func (s *Session) handleRun(args []string) {
+// handleUnset restores the default value of an option of the active module.
+func (s *Session) handleUnset(args []string) {
+    if len(args) != 1 || !s.isModuleActive() {
+        fmt.Println(s.Tui.Red("Usage: unset <option>"))
+        return
+    }
+    module := *s.activeModule
+    om, ok := s.optionManager(module)
+    if !ok {
+        return
+    }
+
+    if err := om.Unset(args[0]); err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    opt, _ := om.Get(args[0])
+    s.forgetOption(module.Prompt(), opt.Name)
+    fmt.Println(s.Tui.Yellow(opt.Name + " => " + opt.Format()["value"]))
+}
+
+// handleReset restores the default value of every option of the active module.
+func (s *Session) handleReset(args []string) {
     if !s.isModuleActive() {
         fmt.Println(s.Tui.Red("No active module."))
         return
     }
-
     module := *s.activeModule
-    prompt := module.Prompt()
+    om, ok := s.optionManager(module)
+    if !ok {
+        return
+    }
+
+    om.Reset()
+    s.forgetOption(module.Prompt(), "")
+    fmt.Println(s.Tui.Yellow("Options of " + module.Prompt() + " restored to their defaults."))
+}
+
+// optionManager returns the OptionManager of a module, printing an error for
+// modules that do not expose one.
+func (s *Session) optionManager(module modules.Module) (*option.OptionManager, bool) {
+    c, ok := module.(modules.Configurable)
+    if !ok {
+        fmt.Println(s.Tui.Red(module.Prompt() + " does not expose its options."))
+        return nil, false
+    }
+    return c.OptionManager(), true
+}
 
-    s.mu.Lock()
-    if _, running := s.runningCancels[prompt]; running {
-        s.mu.Unlock()
-        fmt.Println(s.Tui.Yellow("Module "+prompt+" is already running."))
+// handleRun executes the active module and prints the results.
+func (s *Session) handleRun(args []string) {
+    if !s.isModuleActive() {
+        fmt.Println(s.Tui.Red("No active module."))
         return
     }
-    s.mu.Unlock()
 
-    ctx, cancel := context.WithCancel(context.Background())
+    prompt := (*s.activeModule).Prompt()
+    if !s.checkOptions(*s.activeModule) {
+        return
+    }
 
     runInBackground := len(args) > 0 && args[0] == "&"
     if runInBackground {
-        go func() {
-            module.Start()
-            defer module.Stop()
-            defer func() {
-                s.mu.Lock()
-                delete(s.runningCancels, prompt)
-                s.mu.Unlock()
-            }()
-            module.Run(ctx)
-            fmt.Println(s.Tui.Green("\nModule "+prompt+" finished in background"))
+        job, err := s.startJob(prompt, nil, func(job *jobs.Job) {
+            fmt.Println(s.Tui.Green(fmt.Sprintf("\nJob %d (%s) %s in background", job.ID, job.Module, job.State())))
+            s.reportSkipped(job.Skipped())
             s.Refresh()
-        }()
-        s.mu.Lock()
-        s.runningCancels[prompt] = cancel
-        s.mu.Unlock()
-        fmt.Println(s.Tui.Yellow("Module " + prompt + " started in background."))
+        })
+        if err != nil {
+            fmt.Println(s.Tui.Yellow(err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Job %d (%s) started in background.", job.ID, prompt)))
     } else {
-        // --- Intercept Ctrl+C ---
-        sigs := make(chan os.Signal, 1)
-        signal.Notify(sigs, os.Interrupt, syscall.SIGINT)
-
-        go func() {
-            <-sigs
-            cancel()
-        }()
+        job, err := s.startJob(prompt, nil, nil)
+        if err != nil {
+            fmt.Println(s.Tui.Yellow(err.Error()))
+            return
+        }
 
-        module.Start()
-        defer module.Stop()
+        s.waitJob(job)
+        s.reportSkipped(job.Skipped())
 
-        results := module.Run(ctx)
         fmt.Println(s.Tui.Table(&tui.Table{
             LineSeparator: false,
             Padding:       1,
             MaxWidth:      s.terminalWidth / 3,
-        }, results))
+        }, job.Results()))
+    }
+}
 
-        //Clean up signal manager after execution
-        signal.Stop(sigs)
-        close(sigs)
+// handleCheck validates the options of the active module without running it.
+func (s *Session) handleCheck(args []string) {
+    if !s.isModuleActive() {
+        fmt.Println(s.Tui.Red("No active module."))
+        return
+    }
+    if s.checkOptions(*s.activeModule) {
+        fmt.Println(s.Tui.Green("All options of " + (*s.activeModule).Prompt() + " are valid."))
+    }
+}
+
+// checkOptions prints every missing or invalid option of a module and reports
+// whether it can be run.
+func (s *Session) checkOptions(module modules.Module) bool {
+    errs := modules.Validate(module)
+    if len(errs) == 0 {
+        return true
+    }
+    fmt.Println(s.Tui.Red(fmt.Sprintf("%s cannot run, %d option(s) to fix:", module.Prompt(), len(errs))))
+    for _, err := range errs {
+        fmt.Println(s.Tui.Red("  - " + err.Error()))
     }
+    return false
 }
 
 func (s *Session) handleStop(args []string) {
@@ -237,29 +399,30 @@ func (s *Session) handleStop(args []string) {
         return
     }
 
-    s.mu.Lock()
-    cancel, ok := s.runningCancels[name]
-    s.mu.Unlock()
-    if !ok {
+    running := s.jobs.Running(name)
+    if len(running) == 0 {
         fmt.Println(s.Tui.Red("Module "+name+" is not running."))
         return
     }
 
-    cancel() // invocke ctx.Done() for that module
-    s.mu.Lock()
-    delete(s.runningCancels, name)
-    s.mu.Unlock()
-
-    fmt.Println(s.Tui.Green("Sent stop signal to module "+name))
+    for _, job := range running {
+        job.Kill()
+    }
+    fmt.Println(s.Tui.Green(fmt.Sprintf("Sent stop signal to %d job(s) of module %s", len(running), name)))
 }
 
-
-
 // handleShow displays the output of a module (active or specified by name)
 func (s *Session) handleShow(args []string) {
     var module modules.Module
     var ok bool
 
+    q, args, err := results.ParseArgs(args)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        fmt.Println(s.Tui.Red("Usage: show [module] [--sort col [--desc]] [--filter col=regex ...] [--grep regex] [--limit n] [--offset n] [--columns a,b] [--pager]"))
+        return
+    }
+
     if len(args) > 0 {
         manager := *s.Modules
         module, ok = manager.Get(args[0])
@@ -275,21 +438,57 @@ func (s *Session) handleShow(args []string) {
         module = *s.activeModule
     }
 
-    if module.Running() {
-        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Module %s is running in the background. Try later.", module.Prompt())))
+    if running := s.jobs.Running(module.Prompt()); len(running) > 0 {
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Module %s has %d job(s) running, showing the last completed results (see jobs).", module.Prompt(), len(running))))
+    }
+
+    rows := module.Results()
+    if len(rows) == 0 {
+        fmt.Println(s.Tui.Yellow("No results for " + module.Prompt() + "."))
+        return
+    }
+    view, err := q.Apply(modules.Headers(module), rows)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
         return
     }
 
-    results := module.Results()
-    fmt.Println(s.Tui.Table(&tui.Table{
+    s.printView(q, view, "rows")
+}
+
+// printView prints the rows selected by a query with a footer counting them, through
+// the pager when the query asks for it. noun names the rows in the footer.
+func (s *Session) printView(q results.Query, view results.View, noun string) {
+    table := [][]string{view.Headers, make([]string, len(view.Headers))}
+    for i, h := range view.Headers {
+        table[1][i] = strings.Repeat("-", len(h))
+    }
+    table = append(table, view.Rows...)
+    out := s.Tui.Table(&tui.Table{
         LineSeparator: false,
         Padding:       1,
         MaxWidth:      s.terminalWidth / 3,
-    }, results))
+    }, table)
+
+    footer := fmt.Sprintf("%d of %d %s", len(view.Rows), view.Total, noun)
+    if view.Matched != view.Total {
+        footer += fmt.Sprintf(" (%d matching)", view.Matched)
+    }
+    if q.Offset+len(view.Rows) < view.Matched {
+        footer += fmt.Sprintf(", next page: --offset %d", q.Offset+len(view.Rows))
+    }
+
+    if q.Pager {
+        s.page(out + footer + "\n")
+        return
+    }
+    fmt.Println(out)
+    fmt.Println(s.Tui.Green(footer))
 }
 
 
-// handleSave saves the output of the active module to a file.
+// handleSave saves the output of the active module to a file, with the module's
+// own Save or, with --format, in one of the common export formats.
 func (s *Session) handleSave(args []string) {
 
     if !s.isModuleActive() {
@@ -297,14 +496,34 @@ func (s *Session) handleSave(args []string) {
         return
     }
 
-    if len(args) != 1 {
-        fmt.Println(s.Tui.Red("Usage: save <filename>"))
+    usage := "Usage: save <filename> [--format " + strings.Join(export.Formats, "|") + "]"
+    var filename, format string
+    for i := 0; i < len(args); i++ {
+        switch {
+        case args[i] == "--format" && i+1 < len(args):
+            i++
+            format = args[i]
+        case strings.HasPrefix(args[i], "--format="):
+            format = strings.TrimPrefix(args[i], "--format=")
+        case filename == "" && !strings.HasPrefix(args[i], "--"):
+            filename = args[i]
+        default:
+            filename = ""
+            i = len(args)
+        }
+    }
+    if filename == "" {
+        fmt.Println(s.Tui.Red(usage))
         return
     }
 
-    filename := args[0]
     module := *s.activeModule
-    err := module.Save(filename)
+    var err error
+    if format != "" {
+        err = export.Save(filename, format, modules.ExportData(module))
+    } else {
+        err = module.Save(filename)
+    }
 
     if err != nil {
         fmt.Println(s.Tui.Red("Error saving: " + err.Error()))
@@ -4,6 +4,8 @@ import (
     "fmt"
     "strings"
     "io"
+    "os"
+    "os/exec"
 
     "github.com/chzyer/readline"
 )
@@ -24,37 +26,44 @@ func (s *Session) ReadlineLoop() {
         }
 
         if err != nil && err != readline.ErrInterrupt {
-          fmt.Println(err)
             s.Stop()
-            fmt.Sprintf(s.Tui.Red("Error reading command line: %s"), err)
+            fmt.Println(s.Tui.Red(fmt.Sprintf("Error reading command line: %s", err)))
             return
         }
 
-        line = strings.TrimSpace(line)
-        if line == "" {
-            continue // Skip empty input
+        if strings.TrimSpace(line) != "" {
+            s.ReadLine.SaveHistory(maskCommand(line))
         }
+        s.Execute(line)
 
-        logCommand(line) // Log the user command
+        // Refresh the prompt after executing the command
+        s.Refresh()
+    }
+}
 
-        parts := strings.Fields(line)
-        if len(parts) == 0 {
-            continue
-        }
+// Execute parses a single command line and dispatches it to the matching handler.
+// It is shared by the interactive loop and resource scripts; empty lines and
+// comments starting with '#' are ignored.
+func (s *Session) Execute(line string) {
+    line = strings.TrimSpace(line)
+    if line == "" || strings.HasPrefix(line, "#") {
+        return
+    }
 
-        cmdName := strings.ToLower(parts[0])
-        args := parts[1:]
+    logCommand(maskCommand(line)) // Log the user command, without secrets
 
-        if handler, found := s.commands[cmdName]; found {
-            handler(args) // Execute the matched command handler
-        } else {
-            fmt.Println(s.Tui.Red("Unknown command: " + cmdName))
-        }
+    parts := strings.Fields(line)
+    cmdName := strings.ToLower(parts[0])
+    args := parts[1:]
 
-        // Refresh autocompletion and prompt after executing the command
-        s.ReadLine.Config.AutoComplete = s.commandCompleter()
-        s.Refresh()
+    if handler, found := s.commands[cmdName]; found {
+        handler(args) // Execute the matched command handler
+    } else {
+        fmt.Println(s.Tui.Red("Unknown command: " + cmdName))
     }
+
+    // Refresh autocompletion after executing the command
+    s.ReadLine.Config.AutoComplete = s.commandCompleter()
 }
 
 // updatePrompt updates the CLI prompt depending on the active module.
@@ -75,7 +84,31 @@ func (s *Session) Refresh() {
 
 // Stop ends the session, closes the readline interface, and logs the shutdown.
 func (s *Session) Stop() {
+    if s.scheduler != nil {
+        s.scheduler.Stop()
+    }
+    s.saveWorkspace()
     s.Active = false
     s.ReadLine.Close()
     logSession("[INFO] Session stopped.")
 }
+
+// page shows text through the pager in $PAGER (less -R by default), printing it
+// directly when no pager can be started.
+func (s *Session) page(text string) {
+    pager := os.Getenv("PAGER")
+    if pager == "" {
+        pager = "less -R"
+    }
+    args := strings.Fields(pager)
+    if path, err := exec.LookPath(args[0]); err == nil {
+        cmd := exec.Command(path, args[1:]...)
+        cmd.Stdin = strings.NewReader(text)
+        cmd.Stdout = os.Stdout
+        cmd.Stderr = os.Stderr
+        if err := cmd.Run(); err == nil {
+            return
+        }
+    }
+    fmt.Print(text)
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/modules"
+)
+
+// handleDiff compares two stored runs of a module: diff <module> lists the runs,
+// diff <module> <runA> [runB] [--format table|json] shows the rows added, removed
+// and changed from runA to runB (the latest run by default).
+func (s *Session) handleDiff(args []string) {
+    usage := "Usage: diff <module> [runA [runB]] [--format table|json]"
+    format := "table"
+    var rest []string
+    for i := 0; i < len(args); i++ {
+        switch {
+        case args[i] == "--format" && i+1 < len(args):
+            i++
+            format = args[i]
+        case strings.HasPrefix(args[i], "--format="):
+            format = strings.TrimPrefix(args[i], "--format=")
+        case strings.HasPrefix(args[i], "--"):
+            fmt.Println(s.Tui.Red(usage))
+            return
+        default:
+            rest = append(rest, args[i])
+        }
+    }
+    if len(rest) == 0 || len(rest) > 3 || format != "table" && format != "json" {
+        fmt.Println(s.Tui.Red(usage))
+        return
+    }
+
+    module, ok := (*s.Modules).Get(rest[0])
+    if !ok {
+        fmt.Println(s.Tui.Red("Module not found: " + rest[0]))
+        return
+    }
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws == nil {
+        fmt.Println(s.Tui.Red("No workspace, runs are not stored."))
+        return
+    }
+
+    if len(rest) == 1 {
+        s.listRuns(ws, rest[0])
+        return
+    }
+    refs := append(rest[1:], "latest")
+    from, before, err := ws.LoadRunRef(rest[0], refs[0])
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    to, after, err := ws.LoadRunRef(rest[0], refs[1])
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    s.printDiff(module, from, to, before, after, format)
+}
+
+// printDiff compares two runs and prints the changes as a table or as JSON.
+func (s *Session) printDiff(module modules.Module, from, to string, before, after [][]string, format string) {
+    d, err := modules.Compare(module, before, after)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    d.From, d.To = from, to
+
+    if format == "json" {
+        data, err := json.MarshalIndent(d, "", "  ")
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        fmt.Println(string(data))
+        return
+    }
+
+    fmt.Println(s.Tui.Yellow(fmt.Sprintf("%s: %s -> %s", d.Module, from, to)))
+    if len(d.Changes) > 0 {
+        fmt.Println(s.Tui.Table(&tui.Table{
+            LineSeparator: false,
+            Padding:       1,
+            MaxWidth:      s.terminalWidth / 3,
+        }, d.Table()))
+    }
+    fmt.Println(s.Tui.Green(d.Summary()))
+}
+
+// listRuns prints the stored runs of a module with their number of rows.
+func (s *Session) listRuns(ws *workspace.Workspace, module string) {
+    ids, err := ws.ListRuns(module)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    if len(ids) == 0 {
+        fmt.Println(s.Tui.Yellow("No stored runs of " + module + "."))
+        return
+    }
+
+    table := [][]string{
+        {"  Run", "State", "Rows"},
+        {"  ---", "-----", "----"},
+    }
+    for _, id := range ids {
+        rows := "?"
+        if r, err := ws.LoadRun(module, id); err == nil {
+            rows = strconv.Itoa(len(r))
+        }
+        table = append(table, []string{"  " + id, ws.RunState(module, id), rows})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+}
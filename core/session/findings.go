@@ -0,0 +1,53 @@
+package session
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/utils/results"
+)
+
+// findingsHandler returns the command handler listing one kind of finding
+// (hosts, subdomains, services, urls or vulns).
+//
+// It takes the query flags of show. As a shorthand, "column=regex" stands for
+// --filter column=regex and a plain term for --grep term.
+func (s *Session) findingsHandler(kind string) commandFunc {
+    return func(args []string) {
+        q, args, err := results.ParseArgs(args)
+        if err == nil {
+            for _, arg := range args {
+                if strings.Contains(arg, "=") {
+                    q.Filters = append(q.Filters, arg)
+                } else if q.Grep == "" {
+                    q.Grep = arg
+                } else {
+                    err = fmt.Errorf("more than one grep term (%s, %s)", q.Grep, arg)
+                    break
+                }
+            }
+        }
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            fmt.Println(s.Tui.Red("Usage: " + kind + " [column=regex ...] [term] [--sort col [--desc]] [--filter col=regex ...] [--grep regex] [--limit n] [--offset n] [--columns a,b] [--pager]"))
+            return
+        }
+
+        headers, rows, err := (*s.Modules).Findings().Table(kind)
+        if err != nil {
+            fmt.Println(s.Tui.Red(err.Error()))
+            return
+        }
+        if len(rows) == 0 {
+            fmt.Println(s.Tui.Yellow(fmt.Sprintf("No %s found.", kind)))
+            return
+        }
+
+        view, err := q.Apply(headers, rows)
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        s.printView(q, view, kind)
+    }
+}
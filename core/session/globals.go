@@ -0,0 +1,82 @@
+package session
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/core/config"
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/utils/option"
+)
+
+// handleSetg sets a global option value inherited by every module option with the
+// same name that has not been set locally.
+func (s *Session) handleSetg(args []string) {
+    if len(args) < 2 {
+        fmt.Println(s.Tui.Red("Usage: setg <option> <value>"))
+        return
+    }
+
+    name := strings.ToUpper(args[0])
+    value := strings.Join(args[1:], " ")
+    option.SetGlobal(name, value)
+    s.config.Globals[name] = value
+    s.saveConfig()
+
+    fmt.Println(s.Tui.Yellow(name + " => " + value + " (global)"))
+}
+
+// handleUnsetg removes a global option value.
+func (s *Session) handleUnsetg(args []string) {
+    if len(args) != 1 {
+        fmt.Println(s.Tui.Red("Usage: unsetg <option>"))
+        return
+    }
+
+    name := strings.ToUpper(args[0])
+    if !option.UnsetGlobal(name) {
+        fmt.Println(s.Tui.Red("Global option not set: " + name))
+        return
+    }
+    delete(s.config.Globals, name)
+    s.saveConfig()
+
+    fmt.Println(s.Tui.Yellow("Unset global " + name))
+}
+
+// handleShowg lists the global option values.
+func (s *Session) handleShowg(args []string) {
+    globals := option.Globals()
+    table := [][]string{
+        {"Global options", ""},
+        {"  Name", "Value"},
+        {"  ----", "-----"},
+    }
+    for _, name := range option.GlobalNames() {
+        table = append(table, []string{"  " + name, globals[name]})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: false,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 3,
+    }, table))
+}
+
+// loadConfig reads ~/.oblivion/config and applies its global options.
+func (s *Session) loadConfig() {
+    cfg, err := config.Load()
+    if err != nil {
+        s.logError(err, "loading configuration")
+    }
+    s.config = cfg
+    s.config.ApplyGlobals()
+    s.notifier.SetSinks(cfg.Notify)
+}
+
+// saveConfig writes the session configuration to ~/.oblivion/config.
+func (s *Session) saveConfig() {
+    if err := s.config.Save(); err != nil {
+        fmt.Println(s.Tui.Red("Error saving configuration: " + err.Error()))
+        s.logError(err, "saving configuration")
+    }
+}
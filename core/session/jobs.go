@@ -0,0 +1,284 @@
+package session
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/czz/oblivion/core/jobs"
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/modules"
+)
+
+// handleJobs lists jobs or acts on one: jobs [kill|wait|show <id>].
+func (s *Session) handleJobs(args []string) {
+    if len(args) == 0 || args[0] == "list" {
+        s.listJobs()
+        return
+    }
+
+    if len(args) != 2 {
+        fmt.Println(s.Tui.Red("Usage: jobs [list|kill <id>|wait <id>|show <id>]"))
+        return
+    }
+
+    id, err := strconv.Atoi(args[1])
+    if err != nil {
+        fmt.Println(s.Tui.Red("Invalid job id: " + args[1]))
+        return
+    }
+    job, ok := s.jobs.Get(id)
+    if !ok {
+        fmt.Println(s.Tui.Red("Job not found: " + args[1]))
+        return
+    }
+
+    switch args[0] {
+    case "kill":
+        if job.Ended() {
+            fmt.Println(s.Tui.Red(fmt.Sprintf("Job %d is not running.", id)))
+            return
+        }
+        job.Kill()
+        fmt.Println(s.Tui.Green(fmt.Sprintf("Sent stop signal to job %d (%s)", id, job.Module)))
+    case "wait":
+        if job.Ended() {
+            return
+        }
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Waiting for job %d to finish (ctrl-c to stop waiting)...", id)))
+        s.interruptible(func(done <-chan struct{}) {
+            select {
+            case <-job.Done():
+            case <-done:
+            }
+        })
+    case "show":
+        s.showJob(job)
+    default:
+        fmt.Println(s.Tui.Red("Unknown jobs command: " + args[0]))
+    }
+}
+
+// listJobs prints every job of the session.
+func (s *Session) listJobs() {
+    table := [][]string{
+        {"  ID", "Module", "Started", "Elapsed", "State", "Progress"},
+        {"  --", "------", "-------", "-------", "-----", "--------"},
+    }
+    for _, job := range s.jobs.List() {
+        progress := "-"
+        if status, ok := job.Progress(); ok {
+            progress = status.String()
+        }
+        table = append(table, []string{
+            "  " + strconv.Itoa(job.ID),
+            job.Module,
+            job.Started.Format("15:04:05"),
+            job.Elapsed().Round(time.Second).String(),
+            string(job.State()),
+            progress,
+        })
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+}
+
+// showJob prints the options a job was started with and its results.
+func (s *Session) showJob(job *jobs.Job) {
+    names := make([]string, 0, len(job.Options))
+    for name := range job.Options {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    title := fmt.Sprintf("Job %d: %s (%s)", job.ID, job.Module, job.State())
+    if job.RunID != "" {
+        title += ", run " + job.RunID
+    }
+    fmt.Println(s.Tui.Blue(title))
+    info := [][]string{
+        {"  Option", "Value"},
+        {"  ------", "-----"},
+    }
+    for _, name := range names {
+        info = append(info, []string{"  " + name, job.Options[name]})
+    }
+    if err := job.Err(); err != "" {
+        info = append(info, []string{"  Error", err})
+    }
+    if skipped := job.Skipped(); len(skipped) > 0 {
+        info = append(info, []string{"  Out of scope", strings.Join(skipped, ", ")})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1, MaxWidth: s.terminalWidth / 3}, info))
+
+    if !job.Ended() {
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Job %d is still running, showing the results found so far.", job.ID)))
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: false,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 3,
+    }, job.Results()))
+}
+
+// waitJob blocks until a foreground job ends, printing rows as the module streams
+// them and rendering its progress on a status line when the module reports it.
+// Ctrl+C stops the job.
+func (s *Session) waitJob(job *jobs.Job) {
+    printed := 0
+    status := false
+    refresh := func() {
+        if status {
+            fmt.Print("\r\033[K")
+            status = false
+        }
+        for _, row := range job.Streamed(printed) {
+            fmt.Println(s.Tui.Green("[+] ") + strings.Join(row, "  "))
+            printed++
+        }
+    }
+
+    s.interruptible(func(done <-chan struct{}) {
+        ticker := time.NewTicker(250 * time.Millisecond)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-job.Done():
+                refresh()
+                return
+            case <-done:
+                job.Kill()
+                done = nil
+            case <-ticker.C:
+                refresh()
+                if p, ok := job.Progress(); ok {
+                    fmt.Print(s.Tui.Blue(fmt.Sprintf("[job %d] %s %s", job.ID, job.Module, p)))
+                    status = true
+                }
+            }
+        }
+    })
+}
+
+// startJob runs the named module as a new job. Modules with a factory run on a fresh
+// instance carrying a copy of the current options, so the same module can run several
+// times concurrently; the others run on the registered instance, one job at a time.
+// When options is not nil, the instance runs with those values instead (and the
+// defaults or globals for the others), which requires a factory.
+func (s *Session) startJob(prompt string, options map[string]string, onFinish func(*jobs.Job)) (*jobs.Job, error) {
+    manager := *s.Modules
+    instance, ok := manager.Spawn(prompt)
+    if !ok {
+        if options != nil {
+            return nil, fmt.Errorf("module %s cannot run with its own options", prompt)
+        }
+        if len(s.jobs.Running(prompt)) > 0 {
+            return nil, fmt.Errorf("module %s is already running", prompt)
+        }
+        instance, ok = manager.Get(prompt)
+        if !ok {
+            return nil, fmt.Errorf("module not found: %s", prompt)
+        }
+    }
+    if options != nil {
+        if err := applyOptions(instance, options); err != nil {
+            return nil, err
+        }
+    }
+
+    known := s.knownVulnerabilities()
+    hooks := jobs.Hooks{
+        Scope: s.scope,
+        Finish: func(job *jobs.Job) {
+            s.collectJob(job)
+            s.notifyJob(job, known)
+            if onFinish != nil {
+                onFinish(job)
+            }
+        },
+    }
+
+    // Persist rows in the workspace as they are found
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws != nil {
+        run, err := ws.CreateRun(prompt)
+        if err != nil {
+            s.logError(err, "creating run of "+prompt)
+        } else {
+            hooks.RunID = run.ID
+            hooks.Row = func(job *jobs.Job, row []string) {
+                if err := run.Write(row); err != nil {
+                    s.logError(err, "storing run "+run.ID+" of "+prompt)
+                }
+            }
+            finish := hooks.Finish
+            hooks.Finish = func(job *jobs.Job) {
+                s.logError(run.Finish(string(job.State())), "closing run "+run.ID+" of "+prompt)
+                finish(job)
+            }
+        }
+    }
+
+    return s.jobs.Start(instance, hooks), nil
+}
+
+// applyOptions resets the options of a module instance, sets the given values and
+// validates the result.
+func applyOptions(instance modules.Module, options map[string]string) error {
+    if c, ok := instance.(modules.Configurable); ok {
+        c.OptionManager().Reset()
+    }
+    for _, name := range sortedKeys(options) {
+        result := instance.Set(name, options[name])
+        if len(result) != 2 || result[0] == "Error" {
+            reason := "invalid value"
+            if len(result) == 2 {
+                reason = result[1]
+            }
+            return fmt.Errorf("option %s: %s", name, reason)
+        }
+    }
+    if errs := modules.Validate(instance); len(errs) > 0 {
+        msgs := make([]string, len(errs))
+        for i, err := range errs {
+            msgs[i] = err.Error()
+        }
+        return fmt.Errorf("options: %s", strings.Join(msgs, "; "))
+    }
+    return nil
+}
+
+// collectJob copies the results of a finished job into the registered module, so
+// that show, save, references and the workspace see the latest run, then persists them.
+func (s *Session) collectJob(job *jobs.Job) {
+    s.collectResults(job.Module, job.Instance(), fmt.Sprintf("job %d", job.ID))
+}
+
+// collectResults copies the results of an instance that ran apart, for a job or a
+// scheduled workflow step, into the registered module and persists them.
+func (s *Session) collectResults(prompt string, instance modules.Module, what string) {
+    registered, ok := (*s.Modules).Get(prompt)
+    if !ok || instance == nil {
+        return
+    }
+
+    if instance != registered {
+        src, srcOk := instance.(modules.Persistent)
+        dst, dstOk := registered.(modules.Persistent)
+        if !srcOk || !dstOk {
+            return
+        }
+        data, err := src.Export()
+        if err == nil {
+            err = dst.Import(data)
+        }
+        if err != nil {
+            s.logError(err, "collecting results of "+what)
+            return
+        }
+    }
+    s.saveResults(registered)
+}
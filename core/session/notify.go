@@ -0,0 +1,221 @@
+package session
+
+import (
+    "context"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/czz/oblivion/core/jobs"
+    "github.com/czz/oblivion/core/notify"
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/findings"
+)
+
+// handleNotify manages the notification sinks: notify list, notify add <name> <type>
+// <target> [--on kind,...] [--severity level], notify remove <name>, notify test [name].
+func (s *Session) handleNotify(args []string) {
+    usage := "Usage: notify list | notify add <name> <" + strings.Join(notify.Types, "|") + "> <url|command> [--on " +
+        strings.Join(notify.Kinds, ",") + "] [--severity info|low|medium|high|critical] | notify remove <name> | notify test [name]"
+
+    switch {
+    case len(args) == 0 || args[0] == "list" && len(args) == 1:
+        s.listSinks()
+    case args[0] == "add" && len(args) >= 4:
+        sink, err := parseSink(args[1:])
+        if err == nil {
+            err = s.notifier.Add(sink)
+        }
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        s.config.Notify = s.notifier.Sinks()
+        s.saveConfig()
+        fmt.Println(s.Tui.Yellow("Added " + sink.Type + " sink " + sink.Name))
+    case args[0] == "remove" && len(args) == 2:
+        if !s.notifier.Remove(args[1]) {
+            fmt.Println(s.Tui.Red("Sink not found: " + args[1]))
+            return
+        }
+        s.config.Notify = s.notifier.Sinks()
+        s.saveConfig()
+        fmt.Println(s.Tui.Yellow("Removed sink " + args[1]))
+    case args[0] == "test" && len(args) <= 2:
+        s.testSinks(args[1:])
+    default:
+        fmt.Println(s.Tui.Red(usage))
+    }
+}
+
+// parseSink reads name, type, target and flags. The target is every word up to
+// the first flag, so commands keep their spaces.
+func parseSink(args []string) (notify.Sink, error) {
+    sink := notify.Sink{Name: args[0], Type: strings.ToLower(args[1])}
+    var target []string
+    for i := 2; i < len(args); i++ {
+        switch {
+        case args[i] == "--on" && i+1 < len(args):
+            i++
+            sink.Events = strings.Split(strings.ToLower(args[i]), ",")
+        case args[i] == "--severity" && i+1 < len(args):
+            i++
+            sink.Severity = strings.ToLower(args[i])
+        case strings.HasPrefix(args[i], "--"):
+            return sink, fmt.Errorf("unknown flag %s", args[i])
+        default:
+            target = append(target, args[i])
+        }
+    }
+    sink.Target = strings.Join(target, " ")
+    return sink, nil
+}
+
+// listSinks prints the notification sinks with the events they take.
+func (s *Session) listSinks() {
+    table := [][]string{
+        {"  Name", "Type", "Target", "Events", "Severity"},
+        {"  ----", "----", "------", "------", "--------"},
+    }
+    for _, sink := range s.notifier.Sinks() {
+        events, severity := "all", sink.Severity
+        if len(sink.Events) > 0 {
+            events = strings.Join(sink.Events, ",")
+        }
+        if severity == "" {
+            severity = "info"
+        }
+        table = append(table, []string{"  " + sink.Name, sink.Type, redactTarget(sink.Target), events, severity})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: false,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 3,
+    }, table))
+}
+
+// redactTarget hides the password of a URL target.
+func redactTarget(target string) string {
+    if u, err := url.Parse(target); err == nil && u.User != nil {
+        return u.Redacted()
+    }
+    return target
+}
+
+// testSinks sends a test event to one sink, or to every sink, regardless of the
+// events they take, and prints the outcome of each delivery.
+func (s *Session) testSinks(names []string) {
+    sinks := s.notifier.Sinks()
+    if len(names) == 1 {
+        sink, ok := s.notifier.Get(names[0])
+        if !ok {
+            fmt.Println(s.Tui.Red("Sink not found: " + names[0]))
+            return
+        }
+        sinks = []notify.Sink{sink}
+    }
+    if len(sinks) == 0 {
+        fmt.Println(s.Tui.Yellow("No notification sinks, see notify add."))
+        return
+    }
+
+    for _, sink := range sinks {
+        ctx, cancel := context.WithTimeout(context.Background(), notify.Timeout)
+        err := sink.Send(ctx, notify.Event{
+            Kind:    "test",
+            Title:   "Test notification",
+            Message: "Sink " + sink.Name + " of oblivion works.",
+            Time:    time.Now(),
+        })
+        cancel()
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: sink " + sink.Name + ": " + err.Error()))
+            continue
+        }
+        fmt.Println(s.Tui.Green("Sent test notification to " + sink.Name))
+    }
+}
+
+// sendNotification sends an event to the sinks in background; delivery errors are
+// written to the session log.
+func (s *Session) sendNotification(e notify.Event) {
+    go func() {
+        for _, err := range s.notifier.Notify(e) {
+            s.logError(err, "sending notification")
+        }
+    }()
+}
+
+// notify prints an event of a background run, such as new results of a schedule,
+// and sends it to the sinks.
+func (s *Session) notify(e notify.Event) {
+    s.printEvent(e.Title, e.Message)
+    s.sendNotification(e)
+}
+
+// printEvent prints and logs an event of a background run.
+func (s *Session) printEvent(title, message string) {
+    logInfo(title + ": " + strings.ReplaceAll(message, "\n", "; "))
+    fmt.Println(s.Tui.Yellow("\n[!] " + title))
+    if message != "" {
+        fmt.Println(message)
+    }
+    if s.ReadLine != nil {
+        s.Refresh()
+    }
+}
+
+// knownVulnerabilities returns the IDs of the vulnerabilities already in the
+// findings store, so that a run only notifies the ones it adds.
+func (s *Session) knownVulnerabilities() map[string]bool {
+    known := make(map[string]bool)
+    for _, v := range (*s.Modules).Findings().Vulnerabilities() {
+        known[v.ID] = true
+    }
+    return known
+}
+
+// notifyJob sends the end of a job, then the vulnerabilities it found that were
+// neither known when it started nor notified by another run.
+func (s *Session) notifyJob(job *jobs.Job, known map[string]bool) {
+    title := fmt.Sprintf("Job %d (%s)", job.ID, job.Module)
+    switch job.State() {
+    case jobs.Finished:
+        s.sendNotification(notify.Event{
+            Kind:    notify.Finished,
+            Module:  job.Module,
+            Title:   title + " finished",
+            Message: fmt.Sprintf("%d results in %s", len(job.Results()), job.Elapsed().Round(time.Millisecond)),
+        })
+    case jobs.Failed:
+        s.sendNotification(notify.Event{Kind: notify.Failed, Module: job.Module, Title: title + " failed", Message: job.Err()})
+    }
+    s.notifyFindings(job.Instance(), title, known)
+}
+
+// notifyFindings sends the vulnerabilities of a module not in known and not yet
+// notified in this session.
+func (s *Session) notifyFindings(module modules.Module, title string, known map[string]bool) {
+    finder, ok := module.(modules.Finder)
+    if !ok {
+        return
+    }
+    var found []findings.Vulnerability
+    s.mu.Lock()
+    for _, v := range finder.Vulnerabilities() {
+        if v.ID == "" {
+            v.ID = findings.VulnerabilityID(v.Module, v.Title, v.Target)
+        }
+        if known[v.ID] || s.notified[v.ID] {
+            continue
+        }
+        s.notified[v.ID] = true
+        found = append(found, v)
+    }
+    s.mu.Unlock()
+    if len(found) > 0 {
+        s.sendNotification(notify.FindingEvent(title, module.Prompt(), found))
+    }
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/czz/oblivion/core/profile"
+    "github.com/czz/oblivion/core/tui"
+)
+
+// handleProfile manages the option profiles of the active module:
+// profile save|load|delete <name>, profile list.
+func (s *Session) handleProfile(args []string) {
+    if !s.isModuleActive() {
+        fmt.Println(s.Tui.Red("No active module."))
+        return
+    }
+    if len(args) == 0 || (args[0] != "list" && len(args) != 2) {
+        fmt.Println(s.Tui.Red("Usage: profile list | profile save|load|delete <name>"))
+        return
+    }
+
+    switch args[0] {
+    case "list":
+        s.listProfiles()
+    case "save":
+        s.saveProfile(args[1])
+    case "load":
+        s.loadProfile(args[1])
+    case "delete":
+        prompt := (*s.activeModule).Prompt()
+        if err := profile.Delete(prompt, args[1]); err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Yellow("Deleted profile " + args[1] + " of " + prompt))
+    default:
+        fmt.Println(s.Tui.Red("Usage: profile list | profile save|load|delete <name>"))
+    }
+}
+
+// listProfiles prints the profiles of the active module with their option values.
+func (s *Session) listProfiles() {
+    prompt := (*s.activeModule).Prompt()
+    names, err := profile.List(prompt)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+
+    table := [][]string{
+        {"Profiles of " + prompt, ""},
+        {"  Name", "Options"},
+        {"  ----", "-------"},
+    }
+    for _, name := range names {
+        p, err := profile.Load(prompt, name)
+        if err != nil {
+            table = append(table, []string{"  " + name, "Error: " + err.Error()})
+            continue
+        }
+        table = append(table, []string{"  " + name, formatProfile(p.Options)})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: false,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 2,
+    }, table))
+}
+
+// saveProfile stores the options set in the active module as a profile.
+func (s *Session) saveProfile(name string) {
+    module := *s.activeModule
+    om, ok := s.optionManager(module)
+    if !ok {
+        return
+    }
+
+    values := make(map[string]string)
+    for _, opt := range om.List() {
+        if opt.IsSet() {
+            values[opt.Name] = opt.Raw()
+        }
+    }
+    if err := profile.Save(module.Prompt(), name, values); err != nil {
+        fmt.Println(s.Tui.Red("Error saving profile: " + err.Error()))
+        s.logError(err, "saving profile "+name)
+        return
+    }
+    fmt.Println(s.Tui.Green(fmt.Sprintf("Saved profile %s of %s (%d options)", name, module.Prompt(), len(values))))
+}
+
+// loadProfile resets the options of the active module and sets those of a profile,
+// validating every value as the set command does.
+func (s *Session) loadProfile(name string) {
+    module := *s.activeModule
+    om, ok := s.optionManager(module)
+    if !ok {
+        return
+    }
+    p, err := profile.Load(module.Prompt(), name)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+
+    om.Reset()
+    s.forgetOption(module.Prompt(), "")
+
+    failed := 0
+    for _, key := range sortedKeys(p.Options) {
+        result := module.Set(key, p.Options[key])
+        if len(result) == 2 && result[0] == "Error" {
+            fmt.Println(s.Tui.Red("Error: " + result[1]))
+            failed++
+            continue
+        }
+        if len(result) == 2 {
+            fmt.Println(s.Tui.Yellow(result[0] + " => " + result[1]))
+            s.recordOption(module.Prompt(), key, p.Options[key])
+        }
+    }
+
+    if failed > 0 {
+        fmt.Println(s.Tui.Red(fmt.Sprintf("Loaded profile %s with %d invalid option(s)", name, failed)))
+        return
+    }
+    fmt.Println(s.Tui.Green("Loaded profile " + name))
+}
+
+// formatProfile lists option values as NAME=value, sorted by name.
+func formatProfile(options map[string]string) string {
+    parts := make([]string, 0, len(options))
+    for _, key := range sortedKeys(options) {
+        parts = append(parts, key+"="+options[key])
+    }
+    return strings.Join(parts, " ")
+}
+
+// sortedKeys returns the keys of m in alphabetical order.
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
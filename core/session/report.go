@@ -0,0 +1,115 @@
+package session
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/core/report"
+    "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/export"
+)
+
+// handleReport renders the findings and the results of every module to an HTML or
+// Markdown report, or the vulnerabilities alone as SARIF or JSON lines:
+// report <file> [--format html|md|sarif|jsonl] [--title text ...], or
+// report templates to copy the built-in templates to ~/.oblivion/templates.
+func (s *Session) handleReport(args []string) {
+    usage := "Usage: report <file> [--format " + strings.Join(report.Formats, "|") + "] [--title text] | report templates"
+    if len(args) == 1 && args[0] == "templates" {
+        s.writeReportTemplates()
+        return
+    }
+
+    var filename, format, title string
+    for i := 0; i < len(args); i++ {
+        switch {
+        case args[i] == "--format" && i+1 < len(args):
+            i++
+            format = args[i]
+        case strings.HasPrefix(args[i], "--format="):
+            format = strings.TrimPrefix(args[i], "--format=")
+        case args[i] == "--title" || strings.HasPrefix(args[i], "--title="):
+            // The title runs up to the next flag, as arguments are split on spaces
+            words := []string{strings.TrimPrefix(strings.TrimPrefix(args[i], "--title"), "=")}
+            for i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+                i++
+                words = append(words, args[i])
+            }
+            title = strings.TrimSpace(strings.Join(words, " "))
+        case filename == "" && !strings.HasPrefix(args[i], "--"):
+            filename = args[i]
+        default:
+            filename = ""
+            i = len(args)
+        }
+    }
+    if filename == "" {
+        fmt.Println(s.Tui.Red(usage))
+        return
+    }
+    if format == "" {
+        f, ok := report.FormatOf(filename)
+        if !ok {
+            fmt.Println(s.Tui.Red("Cannot tell the format from " + filename + ", use --format " + strings.Join(report.Formats, "|")))
+            return
+        }
+        format = f
+    }
+
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    workspaceName := "none"
+    if ws != nil {
+        workspaceName = ws.Name
+    }
+    if title == "" {
+        title = "Oblivion report - " + workspaceName
+    }
+
+    include, exclude := s.scope.Entries()
+    scope := append([]string(nil), include...)
+    for _, e := range exclude {
+        scope = append(scope, "!"+e)
+    }
+
+    manager := *s.Modules
+    var mods []export.Data
+    for _, name := range manager.List() {
+        if m, ok := manager.Get(name); ok {
+            mods = append(mods, modules.ExportData(m))
+        }
+    }
+
+    r := report.Build(title, workspaceName, scope, manager.Findings(), mods)
+    origin, err := report.Save(filename, format, r)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error writing report: " + err.Error()))
+        s.logError(err, "writing report")
+        return
+    }
+    if origin == "" {
+        fmt.Println(s.Tui.Green(fmt.Sprintf("Report saved to %s (%d vulnerabilities)", filename, r.Summary.Vulns)))
+        return
+    }
+    fmt.Println(s.Tui.Green(fmt.Sprintf("Report saved to %s (%d hosts, %d vulnerabilities, template: %s)", filename, r.Summary.Hosts, r.Summary.Vulns, origin)))
+}
+
+// writeReportTemplates copies the built-in report templates to the user templates
+// directory, where they can be edited.
+func (s *Session) writeReportTemplates() {
+    written, err := report.WriteTemplates()
+    for _, path := range written {
+        fmt.Println(s.Tui.Yellow("Wrote " + path))
+    }
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    dir, _ := report.TemplatesDir()
+    if len(written) == 0 {
+        fmt.Println(s.Tui.Yellow("Templates already in " + dir + ", remove them to restore the built-in ones."))
+        return
+    }
+    fmt.Println(s.Tui.Green("Edit the templates in " + dir + " to customize the reports."))
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "os/signal"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+)
+
+// maxResourceDepth limits nested resource scripts to avoid infinite inclusion loops.
+const maxResourceDepth = 8
+
+// RunResource executes every line of a resource script through the command dispatcher.
+// Lines starting with '#' are comments; blank lines are skipped.
+func (s *Session) RunResource(path string) error {
+    if s.resourceDepth >= maxResourceDepth {
+        return fmt.Errorf("resource scripts nested too deeply (max %d)", maxResourceDepth)
+    }
+
+    file, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    s.resourceDepth++
+    defer func() { s.resourceDepth-- }()
+
+    logInfo("Running resource script " + path)
+
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fmt.Println(s.Tui.Blue("resource> ") + maskCommand(line))
+        s.Execute(line)
+        if !s.Active {
+            break
+        }
+    }
+    return scanner.Err()
+}
+
+// handleResource runs the commands contained in a resource script file.
+func (s *Session) handleResource(args []string) {
+    if len(args) != 1 {
+        fmt.Println(s.Tui.Red("Usage: resource <file>"))
+        return
+    }
+
+    if err := s.RunResource(args[0]); err != nil {
+        fmt.Println(s.Tui.Red("Error running resource: " + err.Error()))
+        s.logError(err, "running resource script")
+    }
+}
+
+// handleSleep pauses command execution, mainly useful inside resource scripts.
+// The argument is either a number of seconds (e.g. 2.5) or a Go duration (e.g. 1m30s).
+func (s *Session) handleSleep(args []string) {
+    if len(args) != 1 {
+        fmt.Println(s.Tui.Red("Usage: sleep <seconds|duration>"))
+        return
+    }
+
+    d, err := time.ParseDuration(args[0])
+    if err != nil {
+        secs, ferr := strconv.ParseFloat(args[0], 64)
+        if ferr != nil || secs < 0 {
+            fmt.Println(s.Tui.Red("Invalid duration: " + args[0]))
+            return
+        }
+        d = time.Duration(secs * float64(time.Second))
+    }
+
+    s.interruptible(func(done <-chan struct{}) {
+        select {
+        case <-time.After(d):
+        case <-done:
+        }
+    })
+}
+
+// handleWait blocks until the given background module (or every background module) has finished.
+func (s *Session) handleWait(args []string) {
+    name := ""
+    if len(args) > 0 {
+        name = args[0]
+    }
+
+    if !s.isRunning(name) {
+        return
+    }
+    if name == "" {
+        fmt.Println(s.Tui.Yellow("Waiting for background modules to finish (ctrl-c to stop waiting)..."))
+    } else {
+        fmt.Println(s.Tui.Yellow("Waiting for module " + name + " to finish (ctrl-c to stop waiting)..."))
+    }
+
+    s.interruptible(func(done <-chan struct{}) {
+        ticker := time.NewTicker(250 * time.Millisecond)
+        defer ticker.Stop()
+        for s.isRunning(name) {
+            select {
+            case <-ticker.C:
+            case <-done:
+                return
+            }
+        }
+    })
+}
+
+// isRunning reports whether the named module runs in background; with an empty name,
+// whether any module does.
+func (s *Session) isRunning(name string) bool {
+    return len(s.jobs.Running(name)) > 0
+}
+
+// interruptible runs fn, closing the done channel it receives when Ctrl+C is pressed.
+func (s *Session) interruptible(fn func(done <-chan struct{})) {
+    sigs := make(chan os.Signal, 1)
+    signal.Notify(sigs, os.Interrupt, syscall.SIGINT)
+    defer signal.Stop(sigs)
+
+    done := make(chan struct{})
+    finished := make(chan struct{})
+    defer close(finished)
+    go func() {
+        select {
+        case <-sigs:
+            close(done)
+        case <-finished:
+        }
+    }()
+
+    fn(done)
+}
@@ -0,0 +1,284 @@
+package session
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/czz/oblivion/core/jobs"
+    "github.com/czz/oblivion/core/notify"
+    "github.com/czz/oblivion/core/schedule"
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workflow"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/modules"
+    "github.com/czz/oblivion/utils/diff"
+    "github.com/czz/oblivion/utils/scope"
+)
+
+// scheduleTick is how often the schedules are checked.
+const scheduleTick = 5 * time.Second
+
+// startScheduler loads the schedules and starts running the due ones in background.
+func (s *Session) startScheduler() {
+    sc, err := schedule.Open()
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error loading schedules: " + err.Error()))
+        s.logError(err, "loading schedules")
+        return
+    }
+    s.scheduler = sc
+    sc.Start(scheduleTick, s.runSchedule, func(err error) { s.logError(err, "saving schedules") })
+}
+
+// handleSchedule manages the scheduled runs: schedule add <module|workflow file>
+// every <interval>, schedule list, schedule remove <id>.
+func (s *Session) handleSchedule(args []string) {
+    usage := "Usage: schedule list | schedule add <module|workflow.yml> every <interval> | schedule remove <id>"
+    if s.scheduler == nil {
+        fmt.Println(s.Tui.Red("Schedules are not available, see the session log."))
+        return
+    }
+
+    switch {
+    case len(args) == 0 || args[0] == "list" && len(args) == 1:
+        s.listSchedules()
+    case args[0] == "add" && len(args) == 4 && args[2] == "every":
+        s.addSchedule(args[1], args[3])
+    case args[0] == "remove" && len(args) == 2:
+        id, err := strconv.Atoi(args[1])
+        if err == nil {
+            err = s.scheduler.Remove(id)
+        }
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Yellow("Removed schedule " + args[1]))
+    default:
+        fmt.Println(s.Tui.Red(usage))
+    }
+}
+
+// addSchedule schedules a module, with a snapshot of the options set in it, or a
+// workflow file. The first run starts right away.
+func (s *Session) addSchedule(target, every string) {
+    if _, err := schedule.ParseInterval(every); err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+
+    manager := *s.Modules
+    kind := schedule.KindModule
+    var options map[string]string
+    if module, ok := manager.Get(target); ok {
+        options = make(map[string]string)
+        if c, ok := module.(modules.Configurable); ok {
+            for _, opt := range c.OptionManager().List() {
+                if opt.IsSet() {
+                    options[opt.Name] = opt.Raw()
+                }
+            }
+        }
+        // Check the snapshot on a spare instance, as scheduled runs will use it
+        instance, ok := manager.Spawn(target)
+        if !ok {
+            fmt.Println(s.Tui.Red("Module " + target + " cannot be scheduled."))
+            return
+        }
+        if err := applyOptions(instance, options); err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+    } else if _, err := os.Stat(target); err == nil {
+        if _, err := workflow.Load(target, manager); err != nil {
+            fmt.Println(s.Tui.Red("Invalid workflow: " + err.Error()))
+            return
+        }
+        kind = schedule.KindWorkflow
+        if abs, err := filepath.Abs(target); err == nil {
+            target = abs
+        }
+    } else {
+        fmt.Println(s.Tui.Red(target + " is neither a module nor a workflow file."))
+        return
+    }
+
+    sc, err := s.scheduler.Add(kind, target, every, options)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error saving schedule: " + err.Error()))
+        s.logError(err, "saving schedule")
+        return
+    }
+    fmt.Println(s.Tui.Green(fmt.Sprintf("Schedule %d: %s %s every %s, starting now", sc.ID, kind, target, every)))
+}
+
+// listSchedules prints every schedule with its last and next run.
+func (s *Session) listSchedules() {
+    table := [][]string{
+        {"  ID", "Kind", "Target", "Every", "Last run", "Next run", "Options"},
+        {"  --", "----", "------", "-----", "--------", "--------", "-------"},
+    }
+    for _, sc := range s.scheduler.List() {
+        last, next := "never", sc.Next().Format("2006-01-02 15:04")
+        if !sc.LastRun.IsZero() {
+            last = sc.LastRun.Format("2006-01-02 15:04")
+        }
+        if s.scheduler.Running(sc.ID) {
+            next = "running"
+        } else if sc.LastRun.IsZero() {
+            next = "now"
+        }
+        table = append(table, []string{"  " + strconv.Itoa(sc.ID), sc.Kind, sc.Target, sc.Every, last, next, formatProfile(sc.Options)})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: false,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 3,
+    }, table))
+}
+
+// runSchedule runs a due schedule in the current workspace and compares every run
+// it stores with the previous one of the same schedule.
+func (s *Session) runSchedule(sc schedule.Schedule) {
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+
+    runs := make(map[string]string)
+    defer func() {
+        s.logError(s.scheduler.Finish(sc.ID, runs), "saving schedules")
+    }()
+
+    logInfo(fmt.Sprintf("Running schedule %d (%s %s)", sc.ID, sc.Kind, sc.Target))
+    if sc.Kind == schedule.KindWorkflow {
+        s.runScheduledWorkflow(sc, ws, runs)
+        return
+    }
+
+    job, err := s.startJob(sc.Target, sc.Options, nil)
+    if err != nil {
+        s.notify(notify.Event{Kind: notify.Failed, Module: sc.Target, Title: fmt.Sprintf("Schedule %d (%s) failed", sc.ID, sc.Target), Message: err.Error()})
+        return
+    }
+    job.Wait()
+    if job.State() != jobs.Finished {
+        // The sinks already received the end of the job
+        s.printEvent(fmt.Sprintf("Schedule %d (%s) %s", sc.ID, sc.Target, job.State()), job.Err())
+        return
+    }
+    s.compareScheduledRun(sc, ws, sc.Target, sc.Target, job.RunID, runs)
+}
+
+// runScheduledWorkflow runs a scheduled workflow and stores the rows of every step
+// as a run of its module. Steps run on spawned instances, like scheduled modules, so
+// the options and results of the modules in use at the prompt are left alone.
+func (s *Session) runScheduledWorkflow(sc schedule.Schedule, ws *workspace.Workspace, runs map[string]string) {
+    title := fmt.Sprintf("Schedule %d (%s)", sc.ID, filepath.Base(sc.Target))
+    manager := *s.Modules
+    wf, err := workflow.Load(sc.Target, manager)
+    if err != nil {
+        s.notify(notify.Event{Kind: notify.Failed, Title: title + " failed", Message: err.Error()})
+        return
+    }
+
+    runner := workflow.NewRunner(manager)
+    runner.Spawn = true
+    runner.Logf = func(format string, a ...interface{}) { logInfo(fmt.Sprintf(format, a...)) }
+    ctx := scope.WithScope(context.Background(), s.scope, func(target string) {
+        logInfo("Schedule skipped out of scope target " + target)
+    })
+
+    known := s.knownVulnerabilities()
+    for _, res := range runner.Run(ctx, wf) {
+        if res.State != workflow.StateDone {
+            s.notify(notify.Event{
+                Kind:    notify.Failed,
+                Module:  res.Module,
+                Title:   fmt.Sprintf("%s: step %s %s", title, res.ID, res.State),
+                Message: res.Error,
+            })
+            continue
+        }
+        step := fmt.Sprintf("%s step %s", title, res.ID)
+        s.collectResults(res.Module, res.Instance, step)
+        s.notifyFindings(res.Instance, step, known)
+        if ws == nil {
+            continue
+        }
+        run, err := ws.CreateRun(res.Module)
+        if err != nil {
+            s.logError(err, "storing run of "+res.Module)
+            continue
+        }
+        for _, row := range res.Results {
+            s.logError(run.Write(row), "storing run "+run.ID+" of "+res.Module)
+        }
+        s.logError(run.Finish(workspace.RunFinished), "closing run "+run.ID+" of "+res.Module)
+        s.compareScheduledRun(sc, ws, res.ID, res.Module, run.ID, runs)
+    }
+}
+
+// compareScheduledRun compares a stored run with the previous run of the same step
+// of the schedule and notifies the rows added or changed.
+func (s *Session) compareScheduledRun(sc schedule.Schedule, ws *workspace.Workspace, step, prompt, runID string, runs map[string]string) {
+    title := fmt.Sprintf("Schedule %d (%s)", sc.ID, step)
+    if ws == nil || runID == "" {
+        logInfo(title + " finished, no workspace to compare runs")
+        return
+    }
+    key := ws.Name + "/" + step
+    runs[key] = runID
+
+    after, err := ws.LoadRun(prompt, runID)
+    if err != nil {
+        s.logError(err, "loading run "+runID+" of "+prompt)
+        return
+    }
+    prev := sc.Runs[key]
+    before, err := ws.LoadRun(prompt, prev)
+    if prev == "" || err != nil {
+        logInfo(fmt.Sprintf("%s: run %s stored as baseline (%d rows)", title, runID, len(after)))
+        return
+    }
+
+    module, _ := (*s.Modules).Get(prompt)
+    d, err := modules.Compare(module, before, after)
+    if err != nil {
+        s.logError(err, "comparing runs of "+prompt)
+        return
+    }
+    d.From, d.To = prev, runID
+    if d.Added == 0 && d.Changed == 0 {
+        logInfo(fmt.Sprintf("%s: nothing new in run %s (%s)", title, runID, d.Summary()))
+        return
+    }
+    s.notify(notify.Event{
+        Kind:    notify.Change,
+        Module:  prompt,
+        Title:   fmt.Sprintf("%s: new results in run %s", title, runID),
+        Message: describeChanges(d, 5),
+    })
+}
+
+// describeChanges summarizes a diff and lists its first added and changed rows.
+func describeChanges(d *diff.Diff, max int) string {
+    lines := []string{d.Summary()}
+    shown := 0
+    for _, row := range d.Table()[1:] {
+        if row[0] == "-" {
+            continue
+        }
+        if shown == max {
+            lines = append(lines, fmt.Sprintf("... see diff %s %s %s", d.Module, d.From, d.To))
+            break
+        }
+        lines = append(lines, strings.Join(row, " "))
+        shown++
+    }
+    return strings.Join(lines, "\n")
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/core/tui"
+)
+
+// handleScope manages the targets modules may contact:
+// scope add|remove <entry> ..., scope load <file>, scope show, scope clear.
+// Entries starting with '!' are exclusions.
+func (s *Session) handleScope(args []string) {
+    if len(args) == 0 || args[0] == "show" {
+        s.showScope()
+        return
+    }
+
+    switch args[0] {
+    case "add":
+        if len(args) < 2 {
+            fmt.Println(s.Tui.Red("Usage: scope add [!]<entry> ..."))
+            return
+        }
+        for _, entry := range args[1:] {
+            exclude := strings.HasPrefix(entry, "!")
+            if err := s.scope.Add(strings.TrimPrefix(entry, "!"), exclude); err != nil {
+                fmt.Println(s.Tui.Red("Error: " + err.Error()))
+                continue
+            }
+            fmt.Println(s.Tui.Yellow(scopeLabel(exclude) + " " + strings.TrimPrefix(entry, "!")))
+        }
+    case "remove":
+        if len(args) < 2 {
+            fmt.Println(s.Tui.Red("Usage: scope remove [!]<entry> ..."))
+            return
+        }
+        for _, entry := range args[1:] {
+            exclude := strings.HasPrefix(entry, "!")
+            if !s.scope.Remove(strings.TrimPrefix(entry, "!"), exclude) {
+                fmt.Println(s.Tui.Red("Not in scope: " + entry))
+                continue
+            }
+            fmt.Println(s.Tui.Yellow("Removed " + entry))
+        }
+    case "load":
+        if len(args) != 2 {
+            fmt.Println(s.Tui.Red("Usage: scope load <file>"))
+            return
+        }
+        n, err := s.scope.Load(args[1])
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error loading scope: " + err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf("Added %d scope entries from %s", n, args[1])))
+    case "clear":
+        s.scope.Clear()
+        fmt.Println(s.Tui.Yellow("Scope cleared, every target is allowed."))
+    default:
+        fmt.Println(s.Tui.Red("Usage: scope [show|add <entry> ...|remove <entry> ...|load <file>|clear]"))
+        return
+    }
+    s.saveScope()
+}
+
+// showScope prints the include and exclude entries of the scope.
+func (s *Session) showScope() {
+    include, exclude := s.scope.Entries()
+    if len(include) == 0 && len(exclude) == 0 {
+        fmt.Println(s.Tui.Yellow("No scope defined, every target is allowed."))
+        return
+    }
+
+    table := [][]string{
+        {"  Entry", "Type"},
+        {"  -----", "----"},
+    }
+    for _, e := range include {
+        table = append(table, []string{"  " + e, scopeLabel(false)})
+    }
+    for _, e := range exclude {
+        table = append(table, []string{"  !" + e, scopeLabel(true)})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+    if len(include) == 0 {
+        fmt.Println(s.Tui.Yellow("No include entries: every target not excluded is allowed."))
+    }
+}
+
+// scopeLabel names the kind of a scope entry.
+func scopeLabel(exclude bool) string {
+    if exclude {
+        return "Exclude"
+    }
+    return "Include"
+}
+
+// saveScope writes the scope to the current workspace.
+func (s *Session) saveScope() {
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws == nil {
+        return
+    }
+    data, err := s.scope.Export()
+    if err == nil {
+        err = ws.SaveScope(data)
+    }
+    if err != nil {
+        s.logError(err, "saving scope")
+    }
+}
+
+// reportSkipped prints the targets a job skipped because they are out of scope.
+func (s *Session) reportSkipped(skipped []string) {
+    if len(skipped) == 0 {
+        return
+    }
+    const shown = 10
+    list := skipped
+    more := ""
+    if len(list) > shown {
+        list = list[:shown]
+        more = fmt.Sprintf(", ... (%d more)", len(skipped)-shown)
+    }
+    fmt.Println(s.Tui.Yellow(fmt.Sprintf("Skipped %d out of scope target(s): %s%s", len(skipped), strings.Join(list, ", "), more)))
+    logInfo(fmt.Sprintf("Skipped %d out of scope target(s): %s", len(skipped), strings.Join(skipped, ", ")))
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/core/secret"
+    "github.com/czz/oblivion/core/tui"
+)
+
+// handleSecret manages the encrypted secrets referenced in option values as
+// ${SECRET:name}: secret set <name> [value], secret list, secret delete <name>.
+func (s *Session) handleSecret(args []string) {
+    if len(args) == 0 {
+        fmt.Println(s.Tui.Red("Usage: secret list | secret set <name> [value] | secret delete <name>"))
+        return
+    }
+
+    switch {
+    case args[0] == "list" && len(args) == 1:
+        s.listSecrets()
+    case args[0] == "set" && len(args) >= 2:
+        s.setSecret(args[1], strings.Join(args[2:], " "))
+    case args[0] == "delete" && len(args) == 2:
+        if err := secret.Delete(args[1]); err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Yellow("Deleted secret " + args[1]))
+    default:
+        fmt.Println(s.Tui.Red("Usage: secret list | secret set <name> [value] | secret delete <name>"))
+    }
+}
+
+// setSecret stores a secret, reading the value without echo when it is not given.
+func (s *Session) setSecret(name, value string) {
+    if err := secret.ValidateName(name); err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+    if value == "" {
+        if s.ReadLine == nil {
+            fmt.Println(s.Tui.Red("Usage: secret set <name> <value>"))
+            return
+        }
+        v, err := s.ReadLine.ReadPassword("Value of " + name + ": ")
+        if err != nil {
+            fmt.Println(s.Tui.Red("Error: " + err.Error()))
+            return
+        }
+        value = string(v)
+    }
+    if value == "" {
+        fmt.Println(s.Tui.Red("Error: empty secret value"))
+        return
+    }
+
+    if err := secret.Set(name, value); err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        s.logError(err, "saving secret")
+        return
+    }
+    fmt.Println(s.Tui.Yellow("Saved secret " + name + ", use it as ${SECRET:" + name + "}"))
+}
+
+// listSecrets prints the names of the stored secrets; values are never shown.
+func (s *Session) listSecrets() {
+    names, err := secret.List()
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error: " + err.Error()))
+        return
+    }
+
+    table := [][]string{
+        {"Secrets", "", ""},
+        {"  Name", "Value", "Reference"},
+        {"  ----", "-----", "---------"},
+    }
+    for _, name := range names {
+        table = append(table, []string{"  " + name, "****", "${SECRET:" + name + "}"})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+}
+
+// maskCommand hides secret values in a command line before it is written to the
+// history or the session log: the value of "secret set" and every stored secret.
+func maskCommand(line string) string {
+    fields := strings.Fields(line)
+    if len(fields) > 3 && strings.ToLower(fields[0]) == "secret" && fields[1] == "set" {
+        line = strings.Join(fields[:3], " ") + " ****"
+    }
+    return secret.Mask(line)
+}
@@ -4,12 +4,21 @@ import (
     "os"
     "time"
     "unicode/utf8"
-    "context"
     "sync"
 
     "github.com/chzyer/readline"
+    "github.com/czz/oblivion/core/config"
+    "github.com/czz/oblivion/core/jobs"
+    "github.com/czz/oblivion/core/notify"
+    "github.com/czz/oblivion/core/profile"
+    "github.com/czz/oblivion/core/secret"
     "github.com/czz/oblivion/modules"
     "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/schedule"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/target"
 )
 
 // commandFunc defines the function signature for a CLI command handler.
@@ -25,8 +34,16 @@ type Session struct {
     activeModule  *modules.Module            // Currently active module
     terminalWidth int                        // Terminal width in characters
     commands      map[string]commandFunc     // Registered CLI commands
-    runningCancels map[string]context.CancelFunc
+    jobs           *jobs.Manager             // Module runs started in this session
     mu             sync.Mutex
+    resourceDepth  int                       // Nesting level of running resource scripts
+    workspace      *workspace.Workspace      // Current workspace
+    optionValues   map[string]map[string]string // Raw option values set by the user, per module
+    config         *config.Config            // User configuration, including global options
+    scope          *scope.Scope              // Targets modules may contact, saved in the workspace
+    scheduler      *schedule.Scheduler       // Scheduled re-runs, nil when they cannot be loaded
+    notifier       *notify.Notifier          // Notification sinks, saved in the configuration
+    notified       map[string]bool           // IDs of the vulnerabilities already notified
 }
 
 // NewSession initializes and returns a new Session instance.
@@ -38,8 +55,14 @@ func NewSession() *Session {
         Tui:          tui.NewTui(),
         activeModule: nil,
         commands:     make(map[string]commandFunc),
-        runningCancels: make(map[string]context.CancelFunc),
+        jobs:         jobs.NewManager(),
+        optionValues: make(map[string]map[string]string),
+        config:       &config.Config{Globals: make(map[string]string)},
+        scope:        scope.New(),
+        notifier:     notify.New(),
+        notified:     make(map[string]bool),
     }
+    option.SetSecretLookup(secret.Get)
     s.registerCommands()
     return s
 }
@@ -68,11 +91,15 @@ func (s *Session) Start() {
         InterruptPrompt: "^C",
         EOFPrompt:       "exit",
         HistoryLimit:    500,
+        // History is saved by ReadlineLoop with secrets masked
+        DisableAutoSaveHistory: true,
         AutoComplete:    s.commandCompleter(),
     })
     if err != nil {
         panic(err)
     }
+    // Standard input belongs to readline, target lists cannot read "-" from it
+    target.Stdin = nil
 
     s.ReadLine = rl
     s.Active = true
@@ -87,6 +114,19 @@ func (s *Session) Start() {
 
     logInfo("Session started") // Log session start
     s.StartedAt = time.Now()
+
+    s.loadConfig()
+
+    // Restore the last used workspace
+    ws, err := workspace.Create(workspace.Current())
+    if err == nil {
+        err = s.loadWorkspace(ws)
+    }
+    if err != nil {
+        s.logError(err, "opening workspace")
+    }
+
+    s.startScheduler()
 }
 
 // isModuleActive checks if a module is currently selected.
@@ -113,31 +153,119 @@ func (s *Session) calculateActualWidth(str string) int {
 func (s *Session) commandCompleter() *readline.PrefixCompleter {
     useChildren := []readline.PrefixCompleterInterface{}
     setChildren := []readline.PrefixCompleterInterface{}
+    setgChildren := []readline.PrefixCompleterInterface{}
+    unsetgChildren := []readline.PrefixCompleterInterface{}
+    helpChildren := []readline.PrefixCompleterInterface{}
+    secretChildren := []readline.PrefixCompleterInterface{}
 
     manager := *s.Modules
     modulesList := manager.List()
+    optionNames := make(map[string]bool)
     for _, name := range modulesList {
         mod, ok := manager.Get(name)
         if ok {
             useChildren = append(useChildren, readline.PcItem(mod.Prompt()))
+            for _, opt := range mod.Options() {
+                if !optionNames[opt["name"]] {
+                    optionNames[opt["name"]] = true
+                    setgChildren = append(setgChildren, readline.PcItem(opt["name"]))
+                }
+            }
+        }
+    }
+    for _, name := range option.GlobalNames() {
+        unsetgChildren = append(unsetgChildren, readline.PcItem(name))
+    }
+    if names, err := secret.List(); err == nil {
+        for _, name := range names {
+            secretChildren = append(secretChildren, readline.PcItem(name))
+        }
+    }
+
+    if s.isModuleActive() {
+        for _, opt := range (*s.activeModule).Options() {
+            helpChildren = append(helpChildren, readline.PcItem(opt["name"]))
         }
     }
 
     // Base commands available in all contexts
     base := []readline.PrefixCompleterInterface{
-        readline.PcItem("help"),
+        readline.PcItem("help", helpChildren...),
         readline.PcItem("search"),
         readline.PcItem("use", useChildren...),
         readline.PcItem("show", useChildren...),
         readline.PcItem("stop", useChildren...),
+        readline.PcItem("wait", useChildren...),
+        readline.PcItem("jobs",
+            readline.PcItem("list"),
+            readline.PcItem("kill"),
+            readline.PcItem("wait"),
+            readline.PcItem("show"),
+        ),
+        readline.PcItem("setg", setgChildren...),
+        readline.PcItem("unsetg", unsetgChildren...),
+        readline.PcItem("showg"),
+        readline.PcItem("resource"),
+        readline.PcItem("sleep"),
+        readline.PcItem("hosts"),
+        readline.PcItem("subdomains"),
+        readline.PcItem("services"),
+        readline.PcItem("urls"),
+        readline.PcItem("vulns"),
+        readline.PcItem("workflow",
+            readline.PcItem("run"),
+            readline.PcItem("validate"),
+        ),
+        readline.PcItem("scope",
+            readline.PcItem("show"),
+            readline.PcItem("add"),
+            readline.PcItem("remove"),
+            readline.PcItem("load"),
+            readline.PcItem("clear"),
+        ),
+        readline.PcItem("diff", useChildren...),
+        readline.PcItem("notify",
+            readline.PcItem("list"),
+            readline.PcItem("add"),
+            readline.PcItem("remove"),
+            readline.PcItem("test"),
+        ),
+        readline.PcItem("schedule",
+            readline.PcItem("list"),
+            readline.PcItem("add", useChildren...),
+            readline.PcItem("remove"),
+        ),
+        readline.PcItem("report",
+            readline.PcItem("templates"),
+        ),
+        readline.PcItem("secret",
+            readline.PcItem("list"),
+            readline.PcItem("set", secretChildren...),
+            readline.PcItem("delete", secretChildren...),
+        ),
+        readline.PcItem("workspace",
+            readline.PcItem("list"),
+            readline.PcItem("create"),
+            readline.PcItem("use"),
+            readline.PcItem("delete"),
+        ),
         readline.PcItem("exit"),
     }
 
     // Additional commands if a module is currently active
     if s.isModuleActive() {
         m := *s.activeModule
+        unsetChildren := []readline.PrefixCompleterInterface{}
         for _, opt := range m.Options() {
             setChildren = append(setChildren, readline.PcItem(opt["name"]))
+            unsetChildren = append(unsetChildren, readline.PcItem(opt["name"]))
+        }
+
+        profileChildren := []readline.PrefixCompleterInterface{}
+        if names, err := profile.List(m.Prompt()); err == nil {
+            for _, name := range names {
+                profileChildren = append(profileChildren, readline.PcItem(name))
+            }
         }
 
         setRunBackground := []readline.PrefixCompleterInterface{
@@ -147,7 +275,16 @@ func (s *Session) commandCompleter() *readline.PrefixCompleter {
         base = append(base,
             readline.PcItem("options"),
             readline.PcItem("set", setChildren...),
+            readline.PcItem("unset", unsetChildren...),
+            readline.PcItem("reset"),
+            readline.PcItem("profile",
+                readline.PcItem("list"),
+                readline.PcItem("save"),
+                readline.PcItem("load", profileChildren...),
+                readline.PcItem("delete", profileChildren...),
+            ),
             readline.PcItem("run", setRunBackground...),
+            readline.PcItem("check"),
             readline.PcItem("save"),
             readline.PcItem("back"),
         )
@@ -0,0 +1,99 @@
+package session
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workflow"
+    "github.com/czz/oblivion/utils/scope"
+)
+
+// handleWorkflow runs or validates a YAML workflow: workflow run|validate <file>.
+func (s *Session) handleWorkflow(args []string) {
+    if len(args) != 2 || (args[0] != "run" && args[0] != "validate") {
+        fmt.Println(s.Tui.Red("Usage: workflow run|validate <file>"))
+        return
+    }
+
+    manager := *s.Modules
+    wf, err := workflow.Load(args[1], manager)
+    if err != nil {
+        fmt.Println(s.Tui.Red("Invalid workflow: " + err.Error()))
+        return
+    }
+
+    if args[0] == "validate" {
+        table := [][]string{{"  Step", "Module", "Needs", "Timeout"}, {"  ----", "------", "-----", "-------"}}
+        for _, st := range wf.Steps {
+            table = append(table, []string{"  " + st.ID, st.Module, fmt.Sprint(st.Needs), st.Timeout})
+        }
+        fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+        fmt.Println(s.Tui.Green("Workflow " + wf.Name + " is valid."))
+        return
+    }
+
+    if s.isRunning("") {
+        fmt.Println(s.Tui.Red("Modules are running in background, stop them or wait first."))
+        return
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    // --- Intercept Ctrl+C ---
+    sigs := make(chan os.Signal, 1)
+    signal.Notify(sigs, os.Interrupt, syscall.SIGINT)
+    defer signal.Stop(sigs)
+    go func() {
+        select {
+        case <-sigs:
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
+
+    runner := workflow.NewRunner(manager)
+    runner.Logf = func(format string, a ...interface{}) {
+        fmt.Println(s.Tui.Yellow(fmt.Sprintf(format, a...)))
+    }
+
+    logInfo("Running workflow " + wf.Name)
+    fmt.Println(s.Tui.Green("Running workflow " + wf.Name))
+    var mu sync.Mutex
+    var skipped []string
+    seen := make(map[string]bool)
+    ctx = scope.WithScope(ctx, s.scope, func(target string) {
+        mu.Lock()
+        defer mu.Unlock()
+        if !seen[target] {
+            seen[target] = true
+            skipped = append(skipped, target)
+        }
+    })
+    known := s.knownVulnerabilities()
+    results := runner.Run(ctx, wf)
+
+    table := [][]string{{"Step", "Module", "State", "Duration", "Rows", "Error"}}
+    for _, res := range results {
+        table = append(table, []string{
+            res.ID, res.Module, res.State, res.Duration.Round(time.Millisecond).String(),
+            fmt.Sprint(len(res.Results)), res.Error,
+        })
+        if module, ok := manager.Get(res.Module); ok && res.State != workflow.StateSkipped {
+            s.saveResults(module)
+            s.notifyFindings(module, "Workflow "+wf.Name+" step "+res.ID, known)
+        }
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{
+        LineSeparator: true,
+        Padding:       1,
+        MaxWidth:      s.terminalWidth / 3,
+    }, table))
+    s.reportSkipped(skipped)
+}
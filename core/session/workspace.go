@@ -0,0 +1,275 @@
+package session
+
+import (
+    "fmt"
+
+    "github.com/czz/oblivion/core/tui"
+    "github.com/czz/oblivion/core/workspace"
+    "github.com/czz/oblivion/modules"
+)
+
+// handleWorkspace manages workspaces: workspace [list|create|use|delete] [name].
+func (s *Session) handleWorkspace(args []string) {
+    if len(args) == 0 || args[0] == "list" {
+        s.listWorkspaces()
+        return
+    }
+
+    if len(args) != 2 {
+        fmt.Println(s.Tui.Red("Usage: workspace [list|create <name>|use <name>|delete <name>]"))
+        return
+    }
+
+    name := args[1]
+    switch args[0] {
+    case "create":
+        if workspace.Exists(name) {
+            fmt.Println(s.Tui.Red("Workspace " + name + " already exists."))
+            return
+        }
+        if err := s.switchWorkspace(name, true); err != nil {
+            fmt.Println(s.Tui.Red("Error creating workspace: " + err.Error()))
+            s.logError(err, "creating workspace")
+            return
+        }
+        fmt.Println(s.Tui.Green("Created and switched to workspace " + name))
+    case "use":
+        if !workspace.Exists(name) {
+            fmt.Println(s.Tui.Red("Workspace not found: " + name))
+            return
+        }
+        if err := s.switchWorkspace(name, false); err != nil {
+            fmt.Println(s.Tui.Red("Error opening workspace: " + err.Error()))
+            s.logError(err, "opening workspace")
+            return
+        }
+        fmt.Println(s.Tui.Green("Switched to workspace " + name))
+    case "delete":
+        s.mu.Lock()
+        current := s.workspace
+        s.mu.Unlock()
+        if current != nil && current.Name == name {
+            fmt.Println(s.Tui.Red("Cannot delete the current workspace, switch to another one first."))
+            return
+        }
+        if err := workspace.Delete(name); err != nil {
+            fmt.Println(s.Tui.Red("Error deleting workspace: " + err.Error()))
+            return
+        }
+        fmt.Println(s.Tui.Green("Deleted workspace " + name))
+    default:
+        fmt.Println(s.Tui.Red("Unknown workspace command: " + args[0]))
+    }
+}
+
+// listWorkspaces prints all workspaces, marking the current one.
+func (s *Session) listWorkspaces() {
+    names, err := workspace.List()
+    if err != nil {
+        fmt.Println(s.Tui.Red("Error listing workspaces: " + err.Error()))
+        return
+    }
+
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+
+    table := [][]string{{"  Workspace", "Current"}, {"  ---------", "-------"}}
+    for _, n := range names {
+        current := ""
+        if ws != nil && ws.Name == n {
+            current = "*"
+        }
+        table = append(table, []string{"  " + n, current})
+    }
+    fmt.Println(s.Tui.Table(&tui.Table{LineSeparator: false, Padding: 1}, table))
+}
+
+// switchWorkspace saves the current workspace and opens (or creates) another one,
+// resetting every module to the options and results stored in it.
+func (s *Session) switchWorkspace(name string, create bool) error {
+    if s.isRunning("") {
+        return fmt.Errorf("modules are running in background, stop them or wait first")
+    }
+
+    var ws *workspace.Workspace
+    var err error
+    if create {
+        ws, err = workspace.Create(name)
+    } else {
+        ws, err = workspace.Open(name)
+    }
+    if err != nil {
+        return err
+    }
+
+    s.saveWorkspace()
+
+    // Clear the modules so nothing leaks between workspaces
+    (*s.Modules).Reset()
+
+    return s.loadWorkspace(ws)
+}
+
+// loadWorkspace makes ws the current workspace and restores its options and results
+// into the loaded modules.
+func (s *Session) loadWorkspace(ws *workspace.Workspace) error {
+    values, err := ws.LoadOptions()
+    if err != nil {
+        return err
+    }
+
+    manager := *s.Modules
+    for prompt, opts := range values {
+        module, ok := manager.Get(prompt)
+        if !ok {
+            continue
+        }
+        for name, value := range opts {
+            result := module.Set(name, value)
+            if len(result) != 2 || result[0] == "Error" {
+                delete(opts, name)
+                logInfo(fmt.Sprintf("Workspace %s: cannot restore %s.%s", ws.Name, prompt, name))
+            }
+        }
+    }
+
+    for _, prompt := range manager.List() {
+        module, _ := manager.Get(prompt)
+        p, ok := module.(modules.Persistent)
+        if !ok {
+            continue
+        }
+        data, found, err := ws.LoadResults(prompt)
+        if err != nil || !found {
+            continue
+        }
+        if err := p.Import(data); err != nil {
+            s.logError(err, "restoring results of "+prompt)
+        }
+    }
+
+    if data, found, err := ws.LoadFindings(); err != nil {
+        s.logError(err, "loading findings")
+    } else if found {
+        if err := manager.Findings().Import(data); err != nil {
+            s.logError(err, "restoring findings")
+        }
+    }
+
+    // Each workspace has its own scope
+    s.scope.Clear()
+    if data, found, err := ws.LoadScope(); err != nil {
+        s.logError(err, "loading scope")
+    } else if found {
+        if err := s.scope.Import(data); err != nil {
+            s.logError(err, "restoring scope")
+        }
+    }
+
+    s.mu.Lock()
+    s.workspace = ws
+    s.optionValues = values
+    s.mu.Unlock()
+
+    if err := workspace.SetCurrent(ws.Name); err != nil {
+        s.logError(err, "saving current workspace")
+    }
+    logInfo("Workspace " + ws.Name + " loaded")
+    return nil
+}
+
+// recordOption remembers the raw value of a module option and persists it.
+func (s *Session) recordOption(prompt, name, value string) {
+    s.mu.Lock()
+    if s.optionValues[prompt] == nil {
+        s.optionValues[prompt] = make(map[string]string)
+    }
+    s.optionValues[prompt][name] = value
+    s.mu.Unlock()
+
+    s.saveOptions()
+}
+
+// forgetOption drops the recorded value of a module option, or of all its options
+// when name is empty, and persists the change.
+func (s *Session) forgetOption(prompt, name string) {
+    s.mu.Lock()
+    if name == "" {
+        delete(s.optionValues, prompt)
+    } else if s.optionValues[prompt] != nil {
+        delete(s.optionValues[prompt], name)
+    }
+    s.mu.Unlock()
+
+    s.saveOptions()
+}
+
+// saveOptions writes the recorded option values to the current workspace.
+func (s *Session) saveOptions() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.workspace == nil {
+        return
+    }
+    if err := s.workspace.SaveOptions(s.optionValues); err != nil {
+        s.logError(err, "saving workspace options")
+    }
+}
+
+// saveResults writes the results of a module to the current workspace.
+func (s *Session) saveResults(module modules.Module) {
+    p, ok := module.(modules.Persistent)
+    if !ok {
+        return
+    }
+
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws == nil {
+        return
+    }
+
+    data, err := p.Export()
+    if err == nil {
+        err = ws.SaveResults(module.Prompt(), data)
+    }
+    s.logError(err, "saving results of "+module.Prompt())
+
+    s.saveFindings()
+}
+
+// saveFindings writes the session findings to the current workspace.
+func (s *Session) saveFindings() {
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws == nil {
+        return
+    }
+
+    data, err := (*s.Modules).Findings().Export()
+    if err == nil {
+        err = ws.SaveFindings(data)
+    }
+    s.logError(err, "saving findings")
+}
+
+// saveWorkspace persists options and results of every module.
+func (s *Session) saveWorkspace() {
+    s.mu.Lock()
+    ws := s.workspace
+    s.mu.Unlock()
+    if ws == nil {
+        return
+    }
+    s.saveOptions()
+    manager := *s.Modules
+    for _, prompt := range manager.List() {
+        module, _ := manager.Get(prompt)
+        if !module.Running() {
+            s.saveResults(module)
+        }
+    }
+}
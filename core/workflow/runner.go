@@ -0,0 +1,233 @@
+package workflow
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/czz/oblivion/modules"
+)
+
+// Step states reported in StepResult.
+const (
+    StateDone      = "done"
+    StateFailed    = "failed"
+    StateSkipped   = "skipped"
+    StateCancelled = "cancelled"
+)
+
+// StepResult is the outcome of a single step.
+type StepResult struct {
+    ID       string
+    Module   string
+    State    string
+    Error    string
+    Started  time.Time
+    Duration time.Duration
+    Results  [][]string // Snapshot of the module results after the step
+    Instance modules.Module // Module instance the step ran on, nil when it did not run
+}
+
+// Runner executes workflows against the modules of a ModuleManager.
+type Runner struct {
+    Manager *modules.ModuleManager
+    Logf    func(format string, args ...interface{}) // Progress messages, may be nil
+    Spawn   bool                                      // Run every step on a new instance with default options, leaving the registered modules untouched
+
+    moduleLocks map[string]*sync.Mutex
+}
+
+// NewRunner creates a runner for the given module manager.
+func NewRunner(manager *modules.ModuleManager) *Runner {
+    return &Runner{Manager: manager, moduleLocks: make(map[string]*sync.Mutex)}
+}
+
+// Run executes every step, starting each one as soon as the steps it needs have
+// completed, with at most wf.Concurrency steps running at the same time. Steps whose
+// dependencies did not complete successfully are skipped. Results are returned in
+// the order the steps are declared.
+func (r *Runner) Run(ctx context.Context, wf *Workflow) []StepResult {
+    for _, st := range wf.Steps {
+        if _, ok := r.moduleLocks[st.Module]; !ok {
+            r.moduleLocks[st.Module] = &sync.Mutex{}
+        }
+    }
+
+    var mu sync.Mutex
+    results := make(map[string]*StepResult)
+    done := make(map[string]chan struct{})
+    for _, st := range wf.Steps {
+        done[st.ID] = make(chan struct{})
+    }
+
+    // lookup resolves references to finished steps first, then to modules
+    lookup := func(name string) ([][]string, bool) {
+        mu.Lock()
+        res, ok := results[name]
+        mu.Unlock()
+        if ok {
+            return res.Results, true
+        }
+        if module, ok := r.Manager.Get(name); ok {
+            return module.Results(), true
+        }
+        return nil, false
+    }
+
+    sem := make(chan struct{}, wf.Concurrency)
+    var wg sync.WaitGroup
+    for _, st := range wf.Steps {
+        wg.Add(1)
+        go func(st Step) {
+            defer wg.Done()
+            defer close(done[st.ID])
+
+            res := &StepResult{ID: st.ID, Module: st.Module}
+            defer func() {
+                mu.Lock()
+                results[st.ID] = res
+                mu.Unlock()
+            }()
+
+            // Wait for dependencies
+            for _, n := range st.Needs {
+                select {
+                case <-done[n]:
+                case <-ctx.Done():
+                    res.State = StateCancelled
+                    return
+                }
+                mu.Lock()
+                dep := results[n]
+                mu.Unlock()
+                if dep.State != StateDone {
+                    res.State = StateSkipped
+                    res.Error = "dependency " + n + " " + dep.State
+                    r.logf("[%s] skipped: dependency %s %s", st.ID, n, dep.State)
+                    return
+                }
+            }
+
+            select {
+            case sem <- struct{}{}:
+                defer func() { <-sem }()
+            case <-ctx.Done():
+                res.State = StateCancelled
+                return
+            }
+
+            r.runStep(ctx, st, lookup, res)
+        }(st)
+    }
+    wg.Wait()
+
+    out := make([]StepResult, 0, len(wf.Steps))
+    for _, st := range wf.Steps {
+        out = append(out, *results[st.ID])
+    }
+    return out
+}
+
+// runStep applies the options of a step and runs its module.
+func (r *Runner) runStep(ctx context.Context, st Step, lookup modules.ResultsLookup, res *StepResult) {
+    // Steps sharing a module instance must not run concurrently
+    if !r.Spawn {
+        lock := r.moduleLocks[st.Module]
+        lock.Lock()
+        defer lock.Unlock()
+    }
+
+    module, _ := r.Manager.Get(st.Module)
+    if r.Spawn {
+        instance, ok := r.Manager.Spawn(st.Module)
+        if !ok {
+            res.State = StateFailed
+            res.Error = "module " + st.Module + " cannot run on a separate instance"
+            r.logf("[%s] failed: %s", st.ID, res.Error)
+            return
+        }
+        if c, ok := instance.(modules.Configurable); ok {
+            c.OptionManager().Reset()
+        }
+        module = instance
+    }
+    res.Started = time.Now()
+
+    names := make([]string, 0, len(st.Options))
+    for name := range st.Options {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        value, err := st.Options[name].resolve(lookup)
+        if err != nil {
+            res.State = StateFailed
+            res.Error = fmt.Sprintf("option %s: %s", name, err)
+            r.logf("[%s] failed: %s", st.ID, res.Error)
+            return
+        }
+        result := module.Set(name, value)
+        if len(result) != 2 || result[0] == "Error" {
+            res.State = StateFailed
+            res.Error = fmt.Sprintf("option %s: invalid value", name)
+            if len(result) == 2 {
+                res.Error = fmt.Sprintf("option %s: %s", name, result[1])
+            }
+            r.logf("[%s] failed: %s", st.ID, res.Error)
+            return
+        }
+    }
+
+    if errs := modules.Validate(module); len(errs) > 0 {
+        msgs := make([]string, len(errs))
+        for i, err := range errs {
+            msgs[i] = err.Error()
+        }
+        res.State = StateFailed
+        res.Error = "options: " + strings.Join(msgs, "; ")
+        r.logf("[%s] failed: %s", st.ID, res.Error)
+        return
+    }
+
+    stepCtx := ctx
+    if st.timeout > 0 {
+        var cancel context.CancelFunc
+        stepCtx, cancel = context.WithTimeout(ctx, st.timeout)
+        defer cancel()
+    }
+
+    r.logf("[%s] running %s", st.ID, st.Module)
+    res.Instance = module
+    module.Start()
+    rows := module.Run(stepCtx)
+    module.Stop()
+    res.Duration = time.Since(res.Started)
+
+    res.Results = make([][]string, len(rows))
+    for i, row := range rows {
+        res.Results[i] = append([]string(nil), row...)
+    }
+
+    switch {
+    case ctx.Err() != nil:
+        res.State = StateCancelled
+    case stepCtx.Err() == context.DeadlineExceeded:
+        res.State = StateFailed
+        res.Error = "timeout after " + st.timeout.String()
+    case modules.IsErrorResult(rows):
+        res.State = StateFailed
+        res.Error = rows[0][len(rows[0])-1]
+    default:
+        res.State = StateDone
+    }
+    r.logf("[%s] %s in %s (%d rows)", st.ID, res.State, res.Duration.Round(time.Millisecond), len(rows))
+}
+
+func (r *Runner) logf(format string, args ...interface{}) {
+    if r.Logf != nil {
+        r.Logf(format, args...)
+    }
+}
@@ -0,0 +1,305 @@
+package workflow
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/czz/oblivion/modules"
+)
+
+// DefaultConcurrency is the number of independent steps run in parallel when the
+// workflow does not set it.
+const DefaultConcurrency = 4
+
+// Workflow is a chain of module runs described in a YAML file.
+//
+//  name: recon
+//  concurrency: 2
+//  steps:
+//    - id: passive
+//      module: subdomains_search
+//      options:
+//        DOMAIN: example.com
+//    - id: takeover
+//      module: subdomain_takeover
+//      options:
+//        DOMAINS: "@passive"
+type Workflow struct {
+    Name        string `yaml:"name"`
+    Concurrency int    `yaml:"concurrency"`
+    Steps       []Step `yaml:"steps"`
+}
+
+// Step runs one module with the given options.
+type Step struct {
+    ID      string           `yaml:"id"`      // Unique step name, used in references
+    Module  string           `yaml:"module"`  // Module prompt in the ModuleManager
+    Needs   []string         `yaml:"needs"`   // Steps that must finish first
+    Timeout string           `yaml:"timeout"` // Optional Go duration, e.g. 10m
+    Options map[string]Value `yaml:"options"`
+
+    timeout time.Duration
+}
+
+// Value is an option value: either a plain string (which may contain @step or
+// @module references) or a mapping deriving the value from earlier results:
+//
+//  TARGETS:
+//    from: [scan]                          # steps or modules to read results from
+//    match: '^(\S+)\t(\d+)/tcp\t.*HTTP'    # regexp on the tab-joined row, optional
+//    format: 'http://$1:$2'                # expansion of the match groups, optional
+//    column: 0                             # column used when format is empty
+//
+// Without match, $1..$N in format refer to the columns of the row.
+type Value struct {
+    Raw    string   `yaml:"-"`
+    From   []string `yaml:"from"`
+    Column int      `yaml:"column"`
+    Match  string   `yaml:"match"`
+    Format string   `yaml:"format"`
+
+    match *regexp.Regexp
+}
+
+// UnmarshalYAML accepts scalars, lists of scalars (joined with commas) and mappings.
+func (v *Value) UnmarshalYAML(node *yaml.Node) error {
+    switch node.Kind {
+    case yaml.ScalarNode:
+        v.Raw = node.Value
+        return nil
+    case yaml.SequenceNode:
+        var items []string
+        if err := node.Decode(&items); err != nil {
+            return err
+        }
+        v.Raw = strings.Join(items, ",")
+        return nil
+    case yaml.MappingNode:
+        type plain Value
+        var p plain
+        if err := node.Decode(&p); err != nil {
+            return err
+        }
+        *v = Value(p)
+        if len(v.From) == 0 {
+            return fmt.Errorf("line %d: option mapping requires 'from'", node.Line)
+        }
+        return nil
+    }
+    return fmt.Errorf("line %d: unsupported option value", node.Line)
+}
+
+// Load reads and validates a workflow file.
+func Load(path string, manager *modules.ModuleManager) (*Workflow, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var wf Workflow
+    if err := yaml.Unmarshal(data, &wf); err != nil {
+        return nil, fmt.Errorf("parsing %s: %w", path, err)
+    }
+    if wf.Name == "" {
+        wf.Name = path
+    }
+    if err := wf.Validate(manager); err != nil {
+        return nil, err
+    }
+    return &wf, nil
+}
+
+// Validate checks step names, modules, timeouts, regexps and dependencies, adding
+// the dependencies implied by references to Needs.
+func (wf *Workflow) Validate(manager *modules.ModuleManager) error {
+    if len(wf.Steps) == 0 {
+        return fmt.Errorf("workflow %s has no steps", wf.Name)
+    }
+    if wf.Concurrency <= 0 {
+        wf.Concurrency = DefaultConcurrency
+    }
+
+    ids := make(map[string]bool)
+    for i := range wf.Steps {
+        st := &wf.Steps[i]
+        if st.ID == "" {
+            st.ID = fmt.Sprintf("step%d", i+1)
+        }
+        if ids[st.ID] {
+            return fmt.Errorf("duplicate step id %s", st.ID)
+        }
+        ids[st.ID] = true
+        if _, ok := manager.Get(st.Module); !ok {
+            return fmt.Errorf("step %s: module not found: %s", st.ID, st.Module)
+        }
+        if st.Timeout != "" {
+            d, err := time.ParseDuration(st.Timeout)
+            if err != nil || d <= 0 {
+                return fmt.Errorf("step %s: invalid timeout %q", st.ID, st.Timeout)
+            }
+            st.timeout = d
+        }
+    }
+
+    for i := range wf.Steps {
+        st := &wf.Steps[i]
+        deps := make(map[string]bool)
+        for _, n := range st.Needs {
+            if !ids[n] {
+                return fmt.Errorf("step %s needs unknown step %s", st.ID, n)
+            }
+            deps[n] = true
+        }
+
+        for name, val := range st.Options {
+            // Raw "@word" items naming neither a step nor a module are literal values
+            for _, ref := range val.refs() {
+                if ids[ref] {
+                    deps[ref] = true
+                } else if _, ok := manager.Get(ref); !ok && len(val.From) > 0 {
+                    return fmt.Errorf("step %s option %s: unknown reference %s", st.ID, name, ref)
+                }
+            }
+            if val.Match != "" {
+                re, err := regexp.Compile(val.Match)
+                if err != nil {
+                    return fmt.Errorf("step %s option %s: invalid match: %w", st.ID, name, err)
+                }
+                val.match = re
+                st.Options[name] = val
+            }
+        }
+        if deps[st.ID] {
+            return fmt.Errorf("step %s depends on itself", st.ID)
+        }
+
+        st.Needs = st.Needs[:0]
+        for d := range deps {
+            st.Needs = append(st.Needs, d)
+        }
+        sort.Strings(st.Needs)
+    }
+
+    return wf.checkCycles()
+}
+
+// checkCycles fails when the dependencies between steps contain a cycle.
+func (wf *Workflow) checkCycles() error {
+    needs := make(map[string][]string)
+    for _, st := range wf.Steps {
+        needs[st.ID] = st.Needs
+    }
+
+    const (
+        unvisited = iota
+        visiting
+        done
+    )
+    state := make(map[string]int)
+    var visit func(id string) error
+    visit = func(id string) error {
+        switch state[id] {
+        case visiting:
+            return fmt.Errorf("dependency cycle involving step %s", id)
+        case done:
+            return nil
+        }
+        state[id] = visiting
+        for _, n := range needs[id] {
+            if err := visit(n); err != nil {
+                return err
+            }
+        }
+        state[id] = done
+        return nil
+    }
+
+    for _, st := range wf.Steps {
+        if err := visit(st.ID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// refs returns the names referenced by the value.
+func (v Value) refs() []string {
+    if len(v.From) > 0 {
+        return v.From
+    }
+    var names []string
+    for _, item := range strings.Split(v.Raw, ",") {
+        item = strings.TrimSpace(item)
+        if strings.HasPrefix(item, "@") && !strings.HasPrefix(item, "@@") {
+            names = append(names, strings.Split(item[1:], ".")[0])
+        }
+    }
+    return names
+}
+
+// resolve computes the option value from the results available through lookup.
+func (v Value) resolve(lookup modules.ResultsLookup) (string, error) {
+    if len(v.From) == 0 {
+        return modules.ExpandRefs(v.Raw, lookup)
+    }
+
+    var out []string
+    seen := make(map[string]bool)
+    for _, name := range v.From {
+        rows, ok := lookup(name)
+        if !ok {
+            return "", fmt.Errorf("unknown reference %s", name)
+        }
+        for _, row := range rows {
+            val, ok := v.derive(row)
+            val = strings.TrimSpace(val)
+            if ok && val != "" && !seen[val] {
+                seen[val] = true
+                out = append(out, val)
+            }
+        }
+    }
+    if len(out) == 0 {
+        return "", fmt.Errorf("no values derived from %s", strings.Join(v.From, ", "))
+    }
+    return strings.Join(out, ","), nil
+}
+
+// derive extracts the value of a single row; ok is false when the row does not match.
+func (v Value) derive(row []string) (string, bool) {
+    line := strings.Join(row, "\t")
+
+    re := v.match
+    if re == nil {
+        if v.Format == "" {
+            if v.Column < len(row) {
+                return row[v.Column], true
+            }
+            return "", false
+        }
+        // Without match every column is a capture group
+        groups := make([]string, len(row))
+        for i := range groups {
+            groups[i] = "([^\t]*)"
+        }
+        re = regexp.MustCompile("^" + strings.Join(groups, "\t") + "$")
+    }
+
+    m := re.FindStringSubmatchIndex(line)
+    if m == nil {
+        return "", false
+    }
+    if v.Format == "" {
+        if v.Column < len(row) {
+            return row[v.Column], true
+        }
+        return "", false
+    }
+    return string(re.ExpandString(nil, v.Format, line, m)), true
+}
@@ -0,0 +1,422 @@
+package workspace
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// DefaultName is the workspace used when none has been selected yet.
+const DefaultName = "default"
+
+// currentFile stores the name of the last selected workspace inside Root().
+const currentFile = ".current"
+
+// validName restricts workspace names to safe directory names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Workspace is a named directory holding persisted options and module results.
+type Workspace struct {
+    Name string // Workspace name
+    Dir  string // Absolute path of the workspace directory
+}
+
+// Root returns the directory containing all workspaces (~/.oblivion/workspaces).
+func Root() (string, error) {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".oblivion", "workspaces"), nil
+}
+
+// ValidateName checks that name can be used as a workspace name.
+func ValidateName(name string) error {
+    if !validName.MatchString(name) || strings.HasPrefix(name, ".") {
+        return fmt.Errorf("invalid workspace name %q (allowed: letters, digits, '_', '-', '.')", name)
+    }
+    return nil
+}
+
+// Create creates the workspace directory if needed and returns the workspace.
+func Create(name string) (*Workspace, error) {
+    if err := ValidateName(name); err != nil {
+        return nil, err
+    }
+    root, err := Root()
+    if err != nil {
+        return nil, err
+    }
+    dir := filepath.Join(root, name)
+    if err := os.MkdirAll(filepath.Join(dir, "results"), 0755); err != nil {
+        return nil, err
+    }
+    return &Workspace{Name: name, Dir: dir}, nil
+}
+
+// Open returns an existing workspace.
+func Open(name string) (*Workspace, error) {
+    if err := ValidateName(name); err != nil {
+        return nil, err
+    }
+    if !Exists(name) {
+        return nil, fmt.Errorf("workspace %s does not exist", name)
+    }
+    return Create(name)
+}
+
+// Exists reports whether a workspace with the given name exists.
+func Exists(name string) bool {
+    root, err := Root()
+    if err != nil {
+        return false
+    }
+    info, err := os.Stat(filepath.Join(root, name))
+    return err == nil && info.IsDir()
+}
+
+// List returns the names of all workspaces, sorted alphabetically.
+func List() ([]string, error) {
+    root, err := Root()
+    if err != nil {
+        return nil, err
+    }
+    entries, err := os.ReadDir(root)
+    if os.IsNotExist(err) {
+        return []string{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    names := []string{}
+    for _, e := range entries {
+        if e.IsDir() && ValidateName(e.Name()) == nil {
+            names = append(names, e.Name())
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+// Delete removes a workspace and everything stored in it.
+func Delete(name string) error {
+    if err := ValidateName(name); err != nil {
+        return err
+    }
+    if !Exists(name) {
+        return fmt.Errorf("workspace %s does not exist", name)
+    }
+    root, err := Root()
+    if err != nil {
+        return err
+    }
+    return os.RemoveAll(filepath.Join(root, name))
+}
+
+// Current returns the name of the last selected workspace, or DefaultName.
+func Current() string {
+    root, err := Root()
+    if err != nil {
+        return DefaultName
+    }
+    data, err := os.ReadFile(filepath.Join(root, currentFile))
+    if err != nil {
+        return DefaultName
+    }
+    name := strings.TrimSpace(string(data))
+    if ValidateName(name) != nil || !Exists(name) {
+        return DefaultName
+    }
+    return name
+}
+
+// SetCurrent remembers name as the workspace to restore on the next start.
+func SetCurrent(name string) error {
+    root, err := Root()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(root, 0755); err != nil {
+        return err
+    }
+    return os.WriteFile(filepath.Join(root, currentFile), []byte(name+"\n"), 0644)
+}
+
+// SaveOptions stores option values as module prompt -> option name -> raw value.
+func (w *Workspace) SaveOptions(values map[string]map[string]string) error {
+    return writeJSON(filepath.Join(w.Dir, "options.json"), values)
+}
+
+// LoadOptions reads the option values saved with SaveOptions.
+// A workspace without saved options returns an empty map.
+func (w *Workspace) LoadOptions() (map[string]map[string]string, error) {
+    values := make(map[string]map[string]string)
+    data, err := os.ReadFile(filepath.Join(w.Dir, "options.json"))
+    if os.IsNotExist(err) {
+        return values, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, &values); err != nil {
+        return nil, err
+    }
+    return values, nil
+}
+
+// SaveResults stores the exported results of a module.
+func (w *Workspace) SaveResults(module string, data []byte) error {
+    dir := filepath.Join(w.Dir, "results")
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    return os.WriteFile(filepath.Join(dir, module+".json"), data, 0644)
+}
+
+// LoadResults returns the results previously stored for a module.
+// ok is false when nothing has been saved for it.
+func (w *Workspace) LoadResults(module string) (data []byte, ok bool, err error) {
+    data, err = os.ReadFile(filepath.Join(w.Dir, "results", module+".json"))
+    if os.IsNotExist(err) {
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+// SaveFindings stores the exported findings store.
+func (w *Workspace) SaveFindings(data []byte) error {
+    return os.WriteFile(filepath.Join(w.Dir, "findings.json"), data, 0644)
+}
+
+// LoadFindings returns the findings previously stored, if any.
+func (w *Workspace) LoadFindings() (data []byte, ok bool, err error) {
+    data, err = os.ReadFile(filepath.Join(w.Dir, "findings.json"))
+    if os.IsNotExist(err) {
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+// SaveScope stores the exported scope.
+func (w *Workspace) SaveScope(data []byte) error {
+    return os.WriteFile(filepath.Join(w.Dir, "scope.json"), data, 0644)
+}
+
+// LoadScope returns the scope previously stored, if any.
+func (w *Workspace) LoadScope() (data []byte, ok bool, err error) {
+    data, err = os.ReadFile(filepath.Join(w.Dir, "scope.json"))
+    if os.IsNotExist(err) {
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+// Run states stored next to the run files. Only finished runs are picked by the
+// "latest" and "previous" references; runs stored before states were recorded count
+// as finished.
+const (
+    RunRunning   = "running"
+    RunFinished  = "finished"
+    RunCancelled = "cancelled"
+    RunFailed    = "failed"
+)
+
+// RunWriter appends the result rows of a module run, one JSON array per line, as
+// they are found. It is safe for concurrent use.
+type RunWriter struct {
+    ID    string // Run identifier, unique per module
+    mu    sync.Mutex
+    file  *os.File
+    enc   *json.Encoder
+    state string // Path of the file holding the state of the run
+}
+
+// CreateRun creates the file storing a new run of module in runs/<module>/<id>.jsonl,
+// in the running state until Finish records how it ended in <id>.state.
+// The run ID is the start time, made unique with a numeric suffix when needed.
+func (w *Workspace) CreateRun(module string) (*RunWriter, error) {
+    dir := filepath.Join(w.Dir, "runs", module)
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+
+    base := time.Now().Format("20060102-150405")
+    id := base
+    for n := 2; ; n++ {
+        f, err := os.OpenFile(filepath.Join(dir, id+".jsonl"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+        if err == nil {
+            r := &RunWriter{ID: id, file: f, enc: json.NewEncoder(f), state: filepath.Join(dir, id+".state")}
+            if err := os.WriteFile(r.state, []byte(RunRunning), 0644); err != nil {
+                f.Close()
+                return nil, err
+            }
+            return r, nil
+        }
+        if !os.IsExist(err) {
+            return nil, err
+        }
+        id = fmt.Sprintf("%s-%d", base, n)
+    }
+}
+
+// Write appends a row to the run file.
+func (r *RunWriter) Write(row []string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.enc.Encode(row)
+}
+
+// Finish closes the run file and records the state the run ended in.
+func (r *RunWriter) Finish(state string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    err := r.file.Close()
+    if werr := os.WriteFile(r.state, []byte(state), 0644); err == nil {
+        err = werr
+    }
+    return err
+}
+
+// RunState returns the state of a stored run: RunRunning for a run still going or
+// interrupted before it ended, RunFinished for runs stored without a state.
+func (w *Workspace) RunState(module, id string) string {
+    data, err := os.ReadFile(filepath.Join(w.Dir, "runs", module, id+".state"))
+    if err != nil {
+        return RunFinished
+    }
+    return strings.TrimSpace(string(data))
+}
+
+// ListRuns returns the IDs of the stored runs of module, oldest first.
+func (w *Workspace) ListRuns(module string) ([]string, error) {
+    entries, err := os.ReadDir(filepath.Join(w.Dir, "runs", module))
+    if os.IsNotExist(err) {
+        return []string{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    ids := []string{}
+    for _, e := range entries {
+        if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+            ids = append(ids, strings.TrimSuffix(e.Name(), ".jsonl"))
+        }
+    }
+    sort.Strings(ids)
+    return ids, nil
+}
+
+// ResolveRun returns the ID of a stored run of module given its ID, a unique prefix
+// of it, "latest" or "previous" (the finished run before the latest). latest and
+// previous skip the runs that were cancelled, failed or did not end, whose rows are
+// partial.
+func (w *Workspace) ResolveRun(module, ref string) (string, error) {
+    ids, err := w.ListRuns(module)
+    if err != nil {
+        return "", err
+    }
+    if ref == "latest" || ref == "previous" {
+        var finished []string
+        for _, id := range ids {
+            if w.RunState(module, id) == RunFinished {
+                finished = append(finished, id)
+            }
+        }
+        if ref == "latest" {
+            if len(finished) == 0 {
+                return "", fmt.Errorf("no finished runs of %s", module)
+            }
+            return finished[len(finished)-1], nil
+        }
+        if len(finished) < 2 {
+            return "", fmt.Errorf("%s has fewer than two finished runs", module)
+        }
+        return finished[len(finished)-2], nil
+    }
+
+    var matches []string
+    for _, id := range ids {
+        if id == ref {
+            return id, nil
+        }
+        if strings.HasPrefix(id, ref) {
+            matches = append(matches, id)
+        }
+    }
+    switch len(matches) {
+    case 0:
+        return "", fmt.Errorf("run %s of %s not found", ref, module)
+    case 1:
+        return matches[0], nil
+    }
+    return "", fmt.Errorf("run %s of %s is ambiguous: %s", ref, module, strings.Join(matches, ", "))
+}
+
+// LoadRunRef resolves a run reference (see ResolveRun) and returns the run ID and rows.
+func (w *Workspace) LoadRunRef(module, ref string) (string, [][]string, error) {
+    id, err := w.ResolveRun(module, ref)
+    if err != nil {
+        return "", nil, err
+    }
+    rows, err := w.LoadRun(module, id)
+    return id, rows, err
+}
+
+// LoadRun returns the rows stored for a run. Lines that cannot be decoded, such as a
+// truncated last line left by an interrupted run, are skipped.
+func (w *Workspace) LoadRun(module, id string) ([][]string, error) {
+    if ValidateName(id) != nil {
+        return nil, fmt.Errorf("invalid run id %q", id)
+    }
+    f, err := os.Open(filepath.Join(w.Dir, "runs", module, id+".jsonl"))
+    if os.IsNotExist(err) {
+        return nil, fmt.Errorf("run %s of %s not found", id, module)
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    rows := [][]string{}
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    for scanner.Scan() {
+        var row []string
+        if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+            continue
+        }
+        rows = append(rows, row)
+    }
+    return rows, scanner.Err()
+}
+
+// writeJSON atomically writes v as indented JSON to path.
+func writeJSON(path string, v interface{}) error {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
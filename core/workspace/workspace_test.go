@@ -0,0 +1,41 @@
+package workspace
+
+import "testing"
+
+// TestLatestSkipsUnfinishedRuns checks that latest and previous only pick finished
+// runs, while explicit IDs still resolve to any run.
+func TestLatestSkipsUnfinishedRuns(t *testing.T) {
+    ws := &Workspace{Name: "test", Dir: t.TempDir()}
+
+    var ids []string
+    for _, state := range []string{RunFinished, RunFinished, RunCancelled, RunFailed, ""} {
+        run, err := ws.CreateRun("portscanner")
+        if err != nil {
+            t.Fatal(err)
+        }
+        run.Write([]string{"10.0.0.1", "80/tcp"})
+        if state != "" {
+            if err := run.Finish(state); err != nil {
+                t.Fatal(err)
+            }
+        }
+        ids = append(ids, run.ID)
+    }
+
+    if id, _ := ws.ResolveRun("portscanner", "latest"); id != ids[1] {
+        t.Errorf("latest = %s, want %s", id, ids[1])
+    }
+    if id, _ := ws.ResolveRun("portscanner", "previous"); id != ids[0] {
+        t.Errorf("previous = %s, want %s", id, ids[0])
+    }
+    if id, err := ws.ResolveRun("portscanner", ids[2]); err != nil || id != ids[2] {
+        t.Errorf("ResolveRun(%s) = %s, %v", ids[2], id, err)
+    }
+
+    want := []string{RunFinished, RunFinished, RunCancelled, RunFailed, RunRunning}
+    for i, id := range ids {
+        if got := ws.RunState("portscanner", id); got != want[i] {
+            t.Errorf("RunState(%s) = %s, want %s", id, got, want[i])
+        }
+    }
+}
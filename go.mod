@@ -7,6 +7,7 @@ require (
 	github.com/ffuf/ffuf/v2 v2.1.0
 	github.com/go-ping/ping v1.2.0
 	github.com/go-rod/rod v0.116.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
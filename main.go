@@ -1,13 +1,28 @@
 package main
 
 import (
+      "github.com/czz/oblivion/core/cli"
       "github.com/czz/oblivion/core/session"
             "github.com/czz/oblivion/core/tui"
+            "flag"
             "fmt"
+            "os"
 )
 
 
 func main() {
+  // Non-interactive mode: oblivion run <module> [flags]
+  if len(os.Args) > 1 && os.Args[1] == "run" {
+      os.Exit(cli.Run(os.Args[2:]))
+  }
+  // Non-interactive mode: oblivion diff <module> <runA> [runB] [flags]
+  if len(os.Args) > 1 && os.Args[1] == "diff" {
+      os.Exit(cli.Diff(os.Args[2:]))
+  }
+
+  resource := flag.String("r", "", "resource script with commands to run at startup")
+  flag.Parse()
+
   t := tui.NewTui()
 
   fmt.Println(t.Yellow(`
@@ -28,6 +43,13 @@ func main() {
     s := session.NewSession()
     // Start the session
     s.Start()
+    // Run the startup resource script, if any
+    if *resource != "" {
+        if err := s.RunResource(*resource); err != nil {
+            fmt.Println(t.Red("Error running resource: " + err.Error()))
+        }
+        s.Refresh()
+    }
     // Enter the input reading loop
     for s.Active {
         s.ReadlineLoop()
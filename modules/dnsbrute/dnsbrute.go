@@ -1,16 +1,21 @@
 package dnsbrute
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"sort"
 	"strings"
 	"sync"
-	"strconv"
 	"context"
 
+	"github.com/czz/oblivion/utils/findings"
 	"github.com/czz/oblivion/utils/option"
+	"github.com/czz/oblivion/utils/progress"
+	"github.com/czz/oblivion/utils/scope"
+	"github.com/czz/oblivion/utils/stream"
+	"github.com/czz/oblivion/utils/target"
 	"github.com/czz/oblivion/utils/help"
 )
 
@@ -24,24 +29,22 @@ type DNSBrute struct {
 	author        string
 	desc          string
 	prompt        string
+	findings      *findings.Store
+	progress      progress.Counter
 }
 
 // NewDNSBrute creates a new instance
 func NewDNSBrute() *DNSBrute {
 	om := option.NewOptionManager()
 
-	om.Register(option.NewOption("DOMAIN", "", true, "Domain to brute force"))
-	om.Register(option.NewOption("WORDLIST", "", true, "Path to wordlist file"))
-	om.Register(option.NewOption("THREADS", "20", false, "Number of concurrent goroutines"))
-	om.Register(option.NewOption("SUFFIXES", "false", false, "Whether to append numeric suffixes to subdomains"))
+	om.Register(option.NewOption("DOMAIN", []string{}, true, "Domains to brute force").WithType(option.TypeTargets).WithExample("example.com or abc.com,def.com or /pathtofile.txt"))
+	om.Register(option.NewOption("WORDLIST", "", true, "Path to file containing subdomain prefixes").WithType(option.TypePath))
+	om.Register(option.NewOption("THREADS", 20, false, "Number of concurrent goroutines"))
+	om.Register(option.NewOption("SUFFIXES", false, false, "Whether to append numeric suffixes to subdomains").
+		WithDoc("For every subdomain found, also tries the prefix followed by 1-10 as 1, 01, 001, -1, -01 and -001\n(e.g. www1, www-01), i.e. 60 more lookups per subdomain."))
 
 	helpManager := help.NewHelpManager()
-	helpManager.Register("dnsbrute", "Subdomain brute-force module", [][]string{
-		{"DOMAIN", "example.com", "Domain to brute force"},
-		{"WORDLIST", "wordlist.txt", "Path to file containing subdomain prefixes"},
-		{"THREADS", "20", "Number of threads to use"},
-		{"SUFFIXES", "false", "Whether to append numeric suffixes to subdomains"},
-	})
+	helpManager.RegisterOptions("dnsbrute", "Subdomain brute-force module", om)
 
 	return &DNSBrute{
 		optionManager: om,
@@ -58,20 +61,18 @@ func (b *DNSBrute) Run(ctx context.Context) [][]string {
     b.results = []string{}
 
     // Estrai opzioni
-    domainOpt, _ := b.optionManager.Get("DOMAIN")
-    wordlistOpt, _ := b.optionManager.Get("WORDLIST")
-    threadsOpt, _ := b.optionManager.Get("THREADS")
-    suffixesOpt, _ := b.optionManager.Get("SUFFIXES")
-
-    domain := domainOpt.Value.(string)
-    wordlistPath := wordlistOpt.Value.(string)
-    threadCount, err := strconv.Atoi(threadsOpt.Value.(string))
-    if err != nil || threadCount <= 0 {
+    var domains []string
+    for _, t := range b.optionManager.Strings("DOMAIN") {
+        domains = append(domains, target.Host(t))
+    }
+    wordlistPath := b.optionManager.String("WORDLIST")
+    threadCount := b.optionManager.Int("THREADS")
+    if threadCount <= 0 {
         threadCount = 20
     }
-    suffixes, _ := strconv.ParseBool(suffixesOpt.Value.(string))
+    suffixes := b.optionManager.Bool("SUFFIXES")
 
-    if domain == "" || wordlistPath == "" {
+    if len(domains) == 0 || wordlistPath == "" {
         return [][]string{{"Error: DOMAIN or WORDLIST not set"}}
     }
 
@@ -81,13 +82,19 @@ func (b *DNSBrute) Run(ctx context.Context) [][]string {
         return [][]string{{"Error reading wordlist"}}
     }
 
+    b.progress.Start(len(words) * len(domains))
+    defer b.progress.Finish()
+
     // Canale task e canale risultati
-    tasks := make(chan string, len(words))
+    type task struct{ sub, domain string }
+    tasks := make(chan task, len(words)*len(domains))
     resCh := make(chan string, len(words))
 
     // Popola tasks e chiudi
-    for _, w := range words {
-        tasks <- w
+    for _, domain := range domains {
+        for _, w := range words {
+            tasks <- task{w, domain}
+        }
     }
     close(tasks)
 
@@ -103,13 +110,16 @@ func (b *DNSBrute) Run(ctx context.Context) [][]string {
                 case <-ctx.Done():
                     // Contesto cancellato: esci subito
                     return
-                case sub, more := <-tasks:
+                case t, more := <-tasks:
                     if !more {
                         return
                     }
+                    sub, domain := t.sub, t.domain
                     // genera FQDN
                     fqdn := fmt.Sprintf("%s.%s", sub, domain)
-                    if resolveDomain(fqdn) {
+                    resolved := scope.Allowed(ctx, fqdn) && resolveDomain(fqdn)
+                    b.progress.Inc()
+                    if resolved {
                         // invio sicuro sul canale risultati
                         select {
                         case <-ctx.Done():
@@ -120,7 +130,7 @@ func (b *DNSBrute) Run(ctx context.Context) [][]string {
                         if suffixes {
                             for _, suf := range generateNumberSuffixes() {
                                 sfqdn := fmt.Sprintf("%s.%s", sub+suf, domain)
-                                if resolveDomain(sfqdn) {
+                                if scope.Allowed(ctx, sfqdn) && resolveDomain(sfqdn) {
                                     select {
                                     case <-ctx.Done():
                                         return
@@ -152,7 +162,11 @@ func (b *DNSBrute) Run(ctx context.Context) [][]string {
             if !more {
                 goto END
             }
-            unique[r] = struct{}{}
+            if _, ok := unique[r]; !ok {
+                unique[r] = struct{}{}
+                stream.Emit(ctx, []string{r})
+            }
+            b.findings.AddSubdomain(r, b.prompt)
         }
     }
 
@@ -227,6 +241,26 @@ func (b *DNSBrute) Save(path string) error {
 	return nil
 }
 
+// Export serializes the discovered subdomains
+func (b *DNSBrute) Export() ([]byte, error) {
+	return json.Marshal(b.results)
+}
+
+// Import restores subdomains produced by Export
+func (b *DNSBrute) Import(data []byte) error {
+	var results []string
+	if err := json.Unmarshal(data, &results); err != nil {
+		return err
+	}
+	b.results = results
+	return nil
+}
+
+// SetFindings connects the module to the shared findings store
+func (b *DNSBrute) SetFindings(store *findings.Store) {
+	b.findings = store
+}
+
 // Help returns the help text for the module
 func (b *DNSBrute) Help() [][]string {
 	help, _ := b.help.Get(b.prompt)
@@ -243,6 +277,16 @@ func (b *DNSBrute) Options() []map[string]string {
 	return out
 }
 
+// Progress returns the number of words tried so far
+func (b *DNSBrute) Progress() progress.Status {
+	return b.progress.Status()
+}
+
+// OptionManager returns the options of the module
+func (b *DNSBrute) OptionManager() *option.OptionManager {
+	return b.optionManager
+}
+
 // Results returns the results of the brute force operation
 func (b *DNSBrute) Results() [][]string {
 	var res [][]string
@@ -252,17 +296,14 @@ func (b *DNSBrute) Results() [][]string {
 	return res
 }
 
-// Set allows dynamic setting of options
-func (b *DNSBrute) Set(name, value string) []string {
-	//opt, _ := b.optionManager.Get(name)
-	om := *b.optionManager
-	opt, ok := om.Get(name)
+// Headers returns the names of the result columns
+func (b *DNSBrute) Headers() []string {
+	return []string{"SUBDOMAIN"}
+}
 
-	if ok {
-		opt.Set(value)
-		return []string{opt.Name, fmt.Sprintf("%v", opt.Value)}
-	}
-	return []string{"Error", "Option not found"}
+// Set parses and sets an option
+func (b *DNSBrute) Set(name, value string) []string {
+	return b.optionManager.Apply(name, value)
 }
 
 // Metadata
@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
 	"os"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/czz/oblivion/utils/findings"
 	"github.com/czz/oblivion/utils/help"
 	"github.com/czz/oblivion/utils/option"
+	"github.com/czz/oblivion/utils/progress"
+	"github.com/czz/oblivion/utils/scope"
 	"github.com/ffuf/ffuf/v2/pkg/ffuf"
 	"github.com/ffuf/ffuf/v2/pkg/filter"
 
@@ -32,6 +37,9 @@ type FfufWrapper struct {
 	prompt        string                // CLI prompt
 	mu            sync.Mutex            // Protects concurrent access
 	running       bool                  // Indicates if fuzzer is active
+	findings      *findings.Store       // Shared findings store
+	progress      progress.Counter      // Requests sent by the current job
+	finished      time.Time             // End of the last run, time of its findings
 }
 
 // NewFuzzer creates a new FfufWrapper instance with default configuration
@@ -39,9 +47,9 @@ func NewFuzzer() *FfufWrapper {
 	om := option.NewOptionManager()
 
 	// Register HTTP configuration options
-	om.Register(option.NewOption("URL", "", true, "Target URL with FUZZ placeholder"))
-	om.Register(option.NewOption("HEADERS", "", false, "Comma-separated headers (\"Header: Value\")"))
-	om.Register(option.NewOption("METHOD", "GET", false, "HTTP method"))
+	om.Register(option.NewOption("URL", "", true, "Target URL with FUZZ placeholder").WithType(option.TypeURL).WithValidator(validateTarget).WithExample("https://example.com/FUZZ"))
+	om.Register(option.NewOption("HEADERS", "", false, "Comma-separated headers (\"Header: Value\")").WithExample("X-Api-Key: abc,Accept: */*"))
+	om.Register(option.NewOption("METHOD", "GET", false, "HTTP method").WithEnum("GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"))
 	om.Register(option.NewOption("COOKIE", "", false, "Cookie header value"))
 	om.Register(option.NewOption("DATA", "", false, "POST data"))
 	om.Register(option.NewOption("IGNORE_BODY", false, false, "Don't fetch response content"))
@@ -49,8 +57,8 @@ func NewFuzzer() *FfufWrapper {
 	om.Register(option.NewOption("RECURSIVE", false, false, "Enable recursion"))
 	om.Register(option.NewOption("RECURSION_DEPTH", 0, false, "Max recursion depth"))
 	om.Register(option.NewOption("TIMEOUT", 10, false, "HTTP timeout in seconds"))
-	om.Register(option.NewOption("PROXY", "", false, "HTTP Proxy URL"))
-	om.Register(option.NewOption("REPLAY_PROXY", "", false, "Replay matched requests using this proxy"))
+	om.Register(option.NewOption("PROXY", "", false, "HTTP Proxy URL").WithType(option.TypeURL))
+	om.Register(option.NewOption("REPLAY_PROXY", "", false, "Replay matched requests using this proxy").WithType(option.TypeURL))
 
 	// Register general options
 	om.Register(option.NewOption("THREADS", 40, false, "Number of concurrent threads"))
@@ -58,7 +66,7 @@ func NewFuzzer() *FfufWrapper {
 	om.Register(option.NewOption("CUSTOM_CALIBRATION", "", false, "Custom auto-calibration strings (comma-separated)"))
 	om.Register(option.NewOption("MAX_TIME", 0, false, "Maximum running time in seconds for entire process"))
 	om.Register(option.NewOption("MAX_TIME_JOB", 0, false, "Maximum running time in seconds per job"))
-	om.Register(option.NewOption("DELAY", "", false, "Delay between requests (0.1 or 0.1-2.0)"))
+	om.Register(option.NewOption("DELAY", "", false, "Delay between requests in seconds").WithExample("0.1 or 0.1-2.0"))
 	om.Register(option.NewOption("SILENT", true, false, "Silent mode"))
 	om.Register(option.NewOption("STOP_ALL", false, false, "Stop on all error cases"))
 	om.Register(option.NewOption("STOP_ERRORS", false, false, "Stop on spurious errors"))
@@ -66,89 +74,31 @@ func NewFuzzer() *FfufWrapper {
 	om.Register(option.NewOption("VERBOSE", false, false, "Verbose output"))
 
 	// Register matcher options
-	om.Register(option.NewOption("MATCHER_STATUS", "200,301,302,307,401,403,405", false, "Match HTTP status codes"))
-	om.Register(option.NewOption("MATCHER_SIZE", (*int)(nil), false, "Match response size"))
-	om.Register(option.NewOption("MATCHER_LINES", (*int)(nil), false, "Match amount of lines in response"))
-	om.Register(option.NewOption("MATCHER_REGEXP", "", false, "Match regexp"))
-	om.Register(option.NewOption("MATCHER_WORDS", (*int)(nil), false, "Match amount of words in response"))
+	om.Register(option.NewOption("MATCHER_STATUS", "200,301,302,307,401,403,405", false, "Match HTTP status codes").WithExample("200,301 or 200-299 or all"))
+	om.Register(option.NewOption("MATCHER_SIZE", "", false, "Match response size").WithExample("100,200-300"))
+	om.Register(option.NewOption("MATCHER_LINES", "", false, "Match amount of lines in response"))
+	om.Register(option.NewOption("MATCHER_REGEXP", "", false, "Match regexp").WithType(option.TypeRegex))
+	om.Register(option.NewOption("MATCHER_WORDS", "", false, "Match amount of words in response"))
 
 	// Register filter options
 	om.Register(option.NewOption("FILTER_STATUS", "", false, "Filter HTTP status codes"))
-	om.Register(option.NewOption("FILTER_SIZE", (*int)(nil), false, "Filter response size"))
-	om.Register(option.NewOption("FILTER_LINES", (*int)(nil), false, "Filter amount of lines in response"))
-	om.Register(option.NewOption("FILTER_REGEXP", "", false, "Filter regexp"))
-	om.Register(option.NewOption("FILTER_WORDS", (*int)(nil), false, "Filter amount of words in response"))
+	om.Register(option.NewOption("FILTER_SIZE", "", false, "Filter response size").WithExample("100,200-300"))
+	om.Register(option.NewOption("FILTER_LINES", "", false, "Filter amount of lines in response"))
+	om.Register(option.NewOption("FILTER_REGEXP", "", false, "Filter regexp").WithType(option.TypeRegex))
+	om.Register(option.NewOption("FILTER_WORDS", "", false, "Filter amount of words in response"))
 
 	// Register input options
-	om.Register(option.NewOption("WORDLIST", "", true, "Path to wordlist file"))
-	om.Register(option.NewOption("EXTENSIONS", "", false, "Comma-separated extensions"))
+	om.Register(option.NewOption("WORDLIST", "", true, "Path to wordlist file").WithType(option.TypePath))
+	om.Register(option.NewOption("EXTENSIONS", "", false, "Comma-separated extensions").WithExample(".php,.html"))
 	om.Register(option.NewOption("DIRSEARCH_MODE", false, false, "DirSearch wordlist compatibility mode"))
 	om.Register(option.NewOption("IGNORE_COMMENTS", false, false, "Ignore wordlist comments"))
 	om.Register(option.NewOption("INPUT_CMD", "", false, "Command producing the input"))
 	om.Register(option.NewOption("INPUT_NUM", 100, false, "Number of inputs to test with input-cmd"))
-	om.Register(option.NewOption("MODE", "clusterbomb", false, "Multi-wordlist operation mode (clusterbomb/pitchfork)"))
-
-	// Register output options
-  //	om.Register(option.NewOption("OUTPUT_FILE", "", false, "File path to store results"))
+	om.Register(option.NewOption("MODE", "clusterbomb", false, "Multi-wordlist operation mode").WithEnum("clusterbomb", "pitchfork", "sniper"))
 
 	// Setup help documentation
 	helpManager := help.NewHelpManager()
-	helpManager.Register("fuzzer", "Advanced Web Fuzzer", [][]string{
-		// HTTP Options
-		{"URL", "", "Target URL with FUZZ placeholder"},
-		{"HEADERS", "", "Comma-separated headers (\"Header: Value\")"},
-		{"METHOD", "GET", "HTTP method"},
-		{"COOKIE", "", "Cookie header value"},
-		{"DATA", "", "POST data"},
-		{"IGNORE_BODY", "false", "Don't fetch response content"},
-		{"FOLLOW_REDIRECTS", "false", "Follow redirects"},
-		{"RECURSIVE", "false", "Enable recursion"},
-		{"RECURSION_DEPTH", "0", "Max recursion depth"},
-		{"TIMEOUT", "10", "HTTP timeout in seconds"},
-		{"PROXY", "", "HTTP Proxy URL"},
-		{"REPLAY_PROXY", "", "Replay matched requests using this proxy"},
-
-
-		// General Options
-		{"THREADS", "40", "Number of concurrent threads"},
-		{"AUTO_CALIBRATE", "false", "Automatically calibrate filtering options"},
-		{"CUSTOM_CALIBRATION", "", "Custom auto-calibration strings (comma-separated)"},
-		{"MAX_TIME", "0", "Max total runtime in seconds"},
-		{"MAX_TIME_JOB", "0", "Max job time in seconds"},
-		{"DELAY", "", "Delay between requests (e.g., 0.1 or 0.1-2.0)"},
-		{"SILENT", "true", "Silent mode"},
-		{"STOP_ALL", "false", "Stop on all error cases"},
-		{"STOP_ERRORS", "false", "Stop on spurious errors"},
-		{"STOP_FORBIDDEN", "false", "Stop if >95% of responses are 403"},
-		{"VERBOSE", "false", "Verbose output"},
-
-		// Matcher Options
-		{"MATCHER_STATUS", "200,301,302,307,401,403,405", "Match HTTP status codes"},
-		{"MATCHER_SIZE", "", "Match response size"},
-		{"MATCHER_LINES", "", "Match line count"},
-		{"MATCHER_REGEXP", "", "Match using regular expression"},
-		{"MATCHER_WORDS", "", "Match word count"},
-
-		// Filter Options
-		{"FILTER_STATUS", "", "Filter by HTTP status codes"},
-		{"FILTER_SIZE", "", "Filter by response size"},
-		{"FILTER_LINES", "", "Filter by line count"},
-		{"FILTER_REGEXP", "", "Filter using regular expression"},
-		{"FILTER_WORDS", "", "Filter by word count"},
-
-		// Input Options
-		{"WORDLIST", "", "Path to wordlist"},
-		{"EXTENSIONS", "", "Comma-separated list of extensions"},
-		{"DIRSEARCH_MODE", "false", "DirSearch compatibility mode, use with EXTENSIONS"},
-		{"IGNORE_COMMENTS", "false", "Ignore wordlist comments"},
-		{"INPUT_CMD", "", "Command producing the input"},
-		{"INPUT_NUM", "100", "Number of inputs for input-cmd"},
-		{"MODE", "clusterbomb", "Multi-wordlist mode (clusterbomb/pitchfork)"},
-
-		// Output Options
-		//{"OUTPUT_FILE", "", "Path to output file"},
-		// we need output dir for scraping
-	})
+	helpManager.RegisterOptions("fuzzer", "Advanced Web Fuzzer", om)
 
 	return &FfufWrapper{
 		optionManager: om,
@@ -168,16 +118,8 @@ func ConfigFromOptionManager(om *option.OptionManager) *ffuf.ConfigOptions {
 		return conf // Return default config
 	}
 
-	// Helper functions to get options
-	getString := func(name string) string {
-		if opt, ok := om.Get(name); ok {
-			return opt.Value.(string)
-		}
-		return ""
-	}
-
 	getStrings := func(name string) []string {
-		val := getString(name)
+		val := om.String(name)
 		if val == "" {
 			return []string{}
 		}
@@ -185,107 +127,48 @@ func ConfigFromOptionManager(om *option.OptionManager) *ffuf.ConfigOptions {
 	}
 
 	// HTTP Configuration
-	conf.HTTP.URL = getString("URL")
-	conf.HTTP.Method = getString("METHOD")
+	conf.HTTP.URL = om.String("URL")
+	conf.HTTP.Method = om.String("METHOD")
 	conf.HTTP.Headers = getStrings("HEADERS")
 	conf.HTTP.Cookies = getStrings("COOKIE")
-	conf.HTTP.Data = getString("DATA")
-	if opt, ok := om.Get("IGNORE_BODY"); ok {
-		conf.HTTP.IgnoreBody = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("FOLLOW_REDIRECTS"); ok {
-		conf.HTTP.FollowRedirects = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("RECURSIVE"); ok {
-		conf.HTTP.Recursion = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("RECURSION_DEPTH"); ok {
-		conf.HTTP.RecursionDepth = opt.Value.(int)
-	}
-	if opt, ok := om.Get("TIMEOUT"); ok {
-		conf.HTTP.Timeout = opt.Value.(int)
-	}
-	conf.HTTP.ProxyURL = getString("PROXY")
-	conf.HTTP.ReplayProxyURL = getString("REPLAY_PROXY")
+	conf.HTTP.Data = om.String("DATA")
+	conf.HTTP.IgnoreBody = om.Bool("IGNORE_BODY")
+	conf.HTTP.FollowRedirects = om.Bool("FOLLOW_REDIRECTS")
+	conf.HTTP.Recursion = om.Bool("RECURSIVE")
+	conf.HTTP.RecursionDepth = om.Int("RECURSION_DEPTH")
+	conf.HTTP.Timeout = om.Int("TIMEOUT")
+	conf.HTTP.ProxyURL = om.String("PROXY")
+	conf.HTTP.ReplayProxyURL = om.String("REPLAY_PROXY")
 
 	// General Configuration
-	if opt, ok := om.Get("THREADS"); ok {
-		conf.General.Threads = opt.Value.(int)
-	}
-	if opt, ok := om.Get("AUTO_CALIBRATE"); ok {
-		conf.General.AutoCalibration = opt.Value.(bool)
-	}
-	conf.General.Delay = getString("DELAY")
-	if opt, ok := om.Get("SILENT"); ok {
-		conf.General.Quiet = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("STOP_FORBIDDEN"); ok {
-		conf.General.StopOn403 = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("STOP_ALL"); ok {
-		conf.General.StopOnAll = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("STOP_ERRORS"); ok {
-		conf.General.StopOnErrors = opt.Value.(bool)
-	}
+	conf.General.Threads = om.Int("THREADS")
+	conf.General.AutoCalibration = om.Bool("AUTO_CALIBRATE")
+	conf.General.Delay = om.String("DELAY")
+	conf.General.Quiet = om.Bool("SILENT")
+	conf.General.StopOn403 = om.Bool("STOP_FORBIDDEN")
+	conf.General.StopOnAll = om.Bool("STOP_ALL")
+	conf.General.StopOnErrors = om.Bool("STOP_ERRORS")
 
 	// Input Configuration
-	conf.Input.Wordlists = []string{getString("WORDLIST")}
-	conf.Input.Extensions = getString("EXTENSIONS")
-	conf.Input.InputMode = getString("MODE")
-	if opt, ok := om.Get("DIRSEARCH_MODE"); ok {
-		conf.Input.DirSearchCompat = opt.Value.(bool)
-	}
-	if opt, ok := om.Get("IGNORE_COMMENTS"); ok {
-		conf.Input.IgnoreWordlistComments = opt.Value.(bool)
-	}
-
-	// Matcher Configuration
-	conf.Matcher.Status = getString("MATCHER_STATUS")
-
-	// Handle pointer values for numeric matchers
-	if opt, ok := om.Get("MATCHER_SIZE"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Matcher.Size = strconv.Itoa(*intPtr)
-		}
-	}
-	if opt, ok := om.Get("MATCHER_LINES"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Matcher.Lines = strconv.Itoa(*intPtr)
-		}
-	}
-	if opt, ok := om.Get("MATCHER_REGEXP"); ok {
-		conf.Matcher.Regexp = opt.Value.(string)
-	}
-	if opt, ok := om.Get("MATCHER_WORDS"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Matcher.Words = strconv.Itoa(*intPtr)
-		}
-	}
+	conf.Input.Wordlists = []string{om.String("WORDLIST")}
+	conf.Input.Extensions = om.String("EXTENSIONS")
+	conf.Input.InputMode = om.String("MODE")
+	conf.Input.DirSearchCompat = om.Bool("DIRSEARCH_MODE")
+	conf.Input.IgnoreWordlistComments = om.Bool("IGNORE_COMMENTS")
+
+	// Matcher Configuration (sizes, lines and words accept ffuf ranges like 100,200-300)
+	conf.Matcher.Status = om.String("MATCHER_STATUS")
+	conf.Matcher.Size = om.String("MATCHER_SIZE")
+	conf.Matcher.Lines = om.String("MATCHER_LINES")
+	conf.Matcher.Regexp = om.String("MATCHER_REGEXP")
+	conf.Matcher.Words = om.String("MATCHER_WORDS")
 
 	// Filter Configuration
-	conf.Filter.Status = getString("FILTER_STATUS")
-	if opt, ok := om.Get("FILTER_SIZE"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Filter.Size = strconv.Itoa(*intPtr)
-		}
-	}
-	if opt, ok := om.Get("FILTER_LINES"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Filter.Lines = strconv.Itoa(*intPtr)
-		}
-	}
-	if opt, ok := om.Get("FILTER_REGEXP"); ok {
-		conf.Filter.Regexp = opt.Value.(string)
-	}
-	if opt, ok := om.Get("FILTER_WORDS"); ok {
-		if intPtr, ok := opt.Value.(*int); ok && intPtr != nil {
-			conf.Filter.Words = strconv.Itoa(*intPtr)
-		}
-	}
-
-	// Output Configuration
-	conf.Output.OutputFile = getString("OUTPUT_FILE")
+	conf.Filter.Status = om.String("FILTER_STATUS")
+	conf.Filter.Size = om.String("FILTER_SIZE")
+	conf.Filter.Lines = om.String("FILTER_LINES")
+	conf.Filter.Regexp = om.String("FILTER_REGEXP")
+	conf.Filter.Words = om.String("FILTER_WORDS")
 	return conf
 }
 
@@ -306,13 +189,24 @@ func (m *FfufWrapper) Run(ctx context.Context) [][]string {
 	m.errors = nil
 
 	opts := ConfigFromOptionManager(m.optionManager)
+	// Fail fast on a base URL out of scope; every request is checked again by scopedRunner,
+	// as FUZZ can be in the host, e.g. http://FUZZ.example.com
+	if !strings.Contains(hostOf(opts.HTTP.URL), "FUZZ") {
+		if !scope.Allowed(ctx, strings.ReplaceAll(opts.HTTP.URL, "FUZZ", "fuzz")) {
+			return [][]string{{"Error:", "URL is out of scope: " + opts.HTTP.URL}}
+		}
+	}
+
 	conf, err := m.createFFUFConfig(ctx, cancel, opts)
 
 	if err != nil {
 		return [][]string{{"Error:", fmt.Sprintf("Encountered error(s): %s", err)}}
 	}
 
-	job, err := prepareJob(conf)
+	m.progress.Start(0)
+	defer m.progress.Finish()
+
+	job, err := prepareJob(ctx, conf, &m.progress)
 	if err != nil {
 		return [][]string{{"Error:", fmt.Sprintf("Encountered error(s): %s", err)}}
 	}
@@ -339,25 +233,36 @@ func (m *FfufWrapper) Run(ctx context.Context) [][]string {
 		m.results = tableResults(m.ffufResults)
 	}
 
+	// Matches are URLs, not vulnerabilities: they reach the sarif and findings
+	// exports through Vulnerabilities, not the shared store
+	for _, res := range m.ffufResults {
+		m.findings.AddURL(res.Url, int(res.StatusCode), "", m.prompt)
+	}
+	m.finished = time.Now()
+
 	return m.results
 }
 
-// prepareJob initializes ffuf job components
-func prepareJob(conf *ffuf.Config) (*ffuf.Job, error) {
+// prepareJob initializes ffuf job components, reporting progress to counter and
+// streaming results to the emitter carried by ctx
+func prepareJob(ctx context.Context, conf *ffuf.Config, counter *progress.Counter) (*ffuf.Job, error) {
 	var errs ffuf.Multierror
 	job := ffuf.NewJob(conf)
 
 	// Setup input provider
 	job.Input, errs = input.NewInputProvider(conf)
 
-	// Setup HTTP runner
-	job.Runner = runner.NewRunnerByName("http", conf, false)
+	// Setup HTTP runners; redirects are followed by scopedRunner, so that every hop is checked
+	follow := conf.FollowRedirects
+	conf.FollowRedirects = false
+	job.Runner = &scopedRunner{RunnerProvider: runner.NewRunnerByName("http", conf, false), ctx: ctx, follow: follow}
 	if len(conf.ReplayProxyURL) > 0 {
-		job.ReplayRunner = runner.NewRunnerByName("http", conf, true)
+		job.ReplayRunner = &scopedRunner{RunnerProvider: runner.NewRunnerByName("http", conf, true), ctx: ctx, follow: follow}
 	}
+	conf.FollowRedirects = follow
 
 	// Custom output provider
-	job.Output = NewOutput(conf)
+	job.Output = NewOutput(ctx, conf, counter)
 
 	// Initialize scraper
 	newscraper, scraper_err := scraper.FromDir(ffuf.SCRAPERDIR, conf.Scrapers)
@@ -373,6 +278,67 @@ func prepareJob(conf *ffuf.Config) (*ffuf.Job, error) {
 	return job, errs.ErrorOrNil()
 }
 
+// maxRedirects is the number of redirects followed by a single request, as net/http does.
+const maxRedirects = 10
+
+// scopedRunner sends every request of ffuf (words, recursion, replay proxy and each
+// redirect hop) through the scope carried by ctx. Out of scope requests are not sent
+// and are reported as skipped targets.
+type scopedRunner struct {
+	ffuf.RunnerProvider
+	ctx    context.Context
+	follow bool // Follow redirects (FOLLOW_REDIRECTS)
+}
+
+// Execute checks the request URL, sends it and follows its redirects when enabled.
+// The response keeps the original request, as when net/http follows redirects.
+func (r *scopedRunner) Execute(req *ffuf.Request) (ffuf.Response, error) {
+	current := req
+	for hops := 0; ; hops++ {
+		if !scope.Allowed(r.ctx, current.Url) {
+			return ffuf.Response{}, fmt.Errorf("%s is out of scope", current.Url)
+		}
+		resp, err := r.RunnerProvider.Execute(current)
+		if err != nil || !r.follow || resp.GetRedirectLocation(false) == "" {
+			resp.Request = req
+			return resp, err
+		}
+		if hops == maxRedirects {
+			return ffuf.Response{}, fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		next := ffuf.CopyRequest(current)
+		next.Url = resp.GetRedirectLocation(true)
+		if resp.StatusCode != 307 && resp.StatusCode != 308 {
+			next.Method = "GET"
+			next.Data = nil
+		}
+		current = &next
+	}
+}
+
+// hostOf returns the host part of a URL that may contain keywords.
+func hostOf(rawURL string) string {
+	rest := rawURL
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// validateTarget accepts http and https URLs only, as the fuzzer cannot send
+// requests with the other schemes of option.TypeURL.
+func validateTarget(v interface{}) error {
+	u := strings.ToLower(v.(string))
+	if u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return fmt.Errorf("%s: url must start with http:// or https://", v)
+	}
+	return nil
+}
+
 // SetupFilters configures matchers and filters
 func SetupFilters(parseOpts *ffuf.ConfigOptions, conf *ffuf.Config) error {
 	errs := ffuf.NewMultierror()
@@ -439,6 +405,38 @@ func (m *FfufWrapper) Save(filename string) error {
 	return encoder.Encode(m.ffufResults)
 }
 
+// Export serializes the ffuf results
+func (m *FfufWrapper) Export() ([]byte, error) {
+	return json.Marshal(m.ffufResults)
+}
+
+// Import restores ffuf results produced by Export and rebuilds the table
+func (m *FfufWrapper) Import(data []byte) error {
+	var results []ffuf.Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return err
+	}
+	m.ffufResults = results
+	m.results = tableResults(results)
+	m.finished = time.Now()
+	return nil
+}
+
+// SetFindings connects the fuzzer to the shared findings store
+func (m *FfufWrapper) SetFindings(store *findings.Store) {
+	m.findings = store
+}
+
+// Progress returns the requests sent so far by the running job
+func (m *FfufWrapper) Progress() progress.Status {
+	return m.progress.Status()
+}
+
+// OptionManager returns the options of the fuzzer
+func (m *FfufWrapper) OptionManager() *option.OptionManager {
+	return m.optionManager
+}
+
 // Help returns help documentation
 func (m *FfufWrapper) Help() [][]string {
 	h, _ := m.help.Get(m.prompt)
@@ -460,38 +458,60 @@ func (m *FfufWrapper) Results() [][]string {
 	return m.results
 }
 
-// Set updates a configuration option
-func (m *FfufWrapper) Set(name, val string) []string {
-	opt, ok := m.optionManager.Get(name)
-	if !ok {
-		return []string{"Error", "Option not found"}
-	}
-
-	// Handle different option types
-	switch opt.Name {
-	case "MATCHER_SIZE", "MATCHER_LINES", "MATCHER_WORDS",
-		"FILTER_SIZE", "FILTER_LINES", "FILTER_WORDS", "THREADS", "TIMEOUT", "MAX_TIME", "MAX_TIME_JOB", "INPUT_NUM" :
-		if intVal, err := strconv.Atoi(val); err == nil {
-			opt.Set(intVal)
-			return []string{opt.Name, fmt.Sprint(intVal)}
-		}
-		return []string{opt.Name, "Invalid integer value"}
-
-	case "IGNORE_BODY", "FOLLOW_REDIRECTS", "RECURSIVE", "AUTO_CALIBRATE","SILENT",
-	     "STOP_ALL", "STOP_ERRORS", "STOP_FORBIDDEN", "VERBOSE", "DIRSEARCH_MODE", "IGNORE_COMMENTS":
-		if val == "true"{
-		    opt.Set(true)
-				return  []string{opt.Name, "true"}
-		} else if val == "false" {
-     		opt.Set(false)
-				return []string{opt.Name, "false"}
-    }
-    return []string{opt.Name, fmt.Sprintf("Invalid value %s",val)}
-
-	default:
-		opt.Set(val)
-		return []string{opt.Name, fmt.Sprint(val)}
+// Records returns the ffuf results, exported by the json formats
+func (m *FfufWrapper) Records() []interface{} {
+	out := make([]interface{}, len(m.ffufResults))
+	for i, r := range m.ffufResults {
+		out[i] = r
 	}
+	return out
+}
+
+// Vulnerabilities returns the matches of the last run as informational findings,
+// for the sarif and findings exports
+func (m *FfufWrapper) Vulnerabilities() []findings.Vulnerability {
+	out := make([]findings.Vulnerability, 0, len(m.ffufResults))
+	for _, res := range m.ffufResults {
+		v := m.vulnerability(res)
+		v.Timestamp = m.finished
+		out = append(out, v)
+	}
+	return out
+}
+
+// vulnerability describes a match as a finding
+func (m *FfufWrapper) vulnerability(res ffuf.Result) findings.Vulnerability {
+	title := "Fuzzer match"
+	evidence := fmt.Sprintf("status %d, size %d, words %d, lines %d", res.StatusCode, res.ContentLength, res.ContentWords, res.ContentLines)
+	if res.RedirectLocation != "" {
+		evidence += ", redirect to " + res.RedirectLocation
+	}
+	if row := resultRow(res); row[7] != "" {
+		evidence += ", scraper " + row[7]
+	}
+	return findings.Vulnerability{
+		ID:       findings.VulnerabilityID(m.prompt, title, res.Url),
+		Title:    title,
+		Severity: "info",
+		Target:   res.Url,
+		Evidence: evidence,
+		Module:   m.prompt,
+	}
+}
+
+// Headers returns the names of the result columns
+func (m *FfufWrapper) Headers() []string {
+	return []string{"URL", "STATUS", "SIZE", "WORDS", "LINES", "DURATION", "REDIRECT", "SCRAPER"}
+}
+
+// DiffColumns matches the results of two runs by URL, ignoring response times
+func (m *FfufWrapper) DiffColumns() (keys, ignore []string) {
+	return []string{"URL"}, []string{"DURATION"}
+}
+
+// Set parses and updates a configuration option
+func (m *FfufWrapper) Set(name, val string) []string {
+	return m.optionManager.Apply(name, val)
 }
 
 // --- Metadata methods ---
@@ -506,36 +526,30 @@ func (m *FfufWrapper) Stop() error         { m.running = false; return nil }
 // tableResults converts ffuf results to tabular format
 func tableResults(fresults []ffuf.Result) [][]string {
 	var results [][]string
-
 	for _, res := range fresults {
-		var result []string
-    result = append(result,fmt.Sprintf("Url: %s", res.Url))
-		result = append(result,fmt.Sprintf("Status: %d", res.StatusCode))
-		result = append(result,fmt.Sprintf("Size: %d", res.ContentLength))
-		result = append(result,fmt.Sprintf("Words: %d", res.ContentWords))
-		result = append(result,fmt.Sprintf("Lines: %d", res.ContentLines))
-		result = append(result,fmt.Sprintf("Duration: %dms", res.Duration.Milliseconds()))
-
-		results = append(results, result)
-
-		// Add redirect location if exists
-		if res.RedirectLocation != "" {
-			results = append(results,[]string{"",fmt.Sprintf("Redirecting to --> %s",res.RedirectLocation),"","","",""})
-		}
+		results = append(results, resultRow(res))
+	}
+	return results
+}
 
-		// Add result file path if exists
-		if res.ResultFile != "" {
-      results = append(results,[]string{"",fmt.Sprintf("RES --> %s",res.ResultFile),"","","",""})
+// resultRow formats a single ffuf result as a table row (see Headers)
+func resultRow(res ffuf.Result) []string {
+	var scraped []string
+	for k, vslice := range res.ScraperData {
+		for _, v := range vslice {
+			scraped = append(scraped, k+": "+v)
 		}
+	}
+	sort.Strings(scraped)
 
-		// Add scraper data if exists
-		if len(res.ScraperData) > 0 {
-			for k, vslice := range res.ScraperData {
-				for _, v := range vslice {
-					results = append(results,[]string{"",fmt.Sprintf("SCR",res.RedirectLocation),fmt.Sprintf("%s",k),fmt.Sprintf("%s",v),"",""})
-				}
-			}
-		}
+	return []string{
+		res.Url,
+		strconv.FormatInt(res.StatusCode, 10),
+		strconv.FormatInt(res.ContentLength, 10),
+		strconv.FormatInt(res.ContentWords, 10),
+		strconv.FormatInt(res.ContentLines, 10),
+		fmt.Sprintf("%dms", res.Duration.Milliseconds()),
+		res.RedirectLocation,
+		strings.Join(scraped, "; "),
 	}
-	return results
 }
@@ -1,19 +1,29 @@
 package fuzzer
 
 import (
+	"context"
+
+	"github.com/czz/oblivion/utils/progress"
+	"github.com/czz/oblivion/utils/stream"
 	"github.com/ffuf/ffuf/v2/pkg/output"
 	"github.com/ffuf/ffuf/v2/pkg/ffuf"
 )
 
 // Output wraps ffuf's Stdoutput to provide a cleaner interface for use.
 type Output struct {
-	inner *output.Stdoutput
+	inner    *output.Stdoutput
+	progress *progress.Counter
+	ctx      context.Context
 }
 
 // NewOutput creates a new Output instance using the provided ffuf config.
-func NewOutput(conf *ffuf.Config) *Output {
+// Progress updates are reported to counter instead of stdout and results are
+// streamed to the emitter carried by ctx.
+func NewOutput(ctx context.Context, conf *ffuf.Config, counter *progress.Counter) *Output {
 	return &Output{
-		inner: output.NewStdoutput(conf),
+		inner:    output.NewStdoutput(conf),
+		progress: counter,
+		ctx:      ctx,
 	}
 }
 
@@ -42,9 +52,11 @@ func (o *Output) SetCurrentResults(results []ffuf.Result) {
 	o.inner.SetCurrentResults(results)
 }
 
-// Progress updates the progress output with current status.
+// Progress records the current status in the progress counter.
 func (o *Output) Progress(status ffuf.Progress) {
-	o.inner.Progress(status)
+	if o.progress != nil {
+		o.progress.Set(status.ReqCount, status.ReqTotal)
+	}
 }
 
 // Info logs an informational message.
@@ -104,6 +116,7 @@ func (o *Output) Result(resp ffuf.Response) {
 
 	// Append the result to the output's current results
 	o.inner.CurrentResults = append(o.inner.CurrentResults, sResult)
+	stream.Emit(o.ctx, resultRow(sResult))
 }
 
 // PrintResult prints a single result using the inner output mechanism.
@@ -1,25 +1,85 @@
 package modules
 
-import "sort"
+import (
+    "sort"
+
+    "github.com/czz/oblivion/utils/findings"
+)
 
 // ModuleManager is responsible for managing modules dynamically.
 type ModuleManager struct {
     modules map[string]Module
     positions map[int]string
+    findings *findings.Store // Findings shared by all registered modules
+    factories map[string]Factory // Constructors used to spawn independent instances
 }
 
 // NewManager creates a new ModuleManager instance.
 func NewModuleManager() *ModuleManager {
     return &ModuleManager{
         modules: make(map[string]Module),
+        findings: findings.NewStore(),
+        factories: make(map[string]Factory),
     }
 }
 
-// Register adds a module to the manager.
+// Register adds a module to the manager, connecting it to the shared findings store.
 func (m *ModuleManager) Register(module Module) {
+    if r, ok := module.(findings.Reporter); ok {
+        r.SetFindings(m.findings)
+    }
     m.modules[module.Prompt()] = module
 }
 
+// RegisterFactory registers the module built by factory and remembers the factory
+// so that independent instances can be spawned later.
+func (m *ModuleManager) RegisterFactory(factory Factory) {
+    module := factory()
+    m.Register(module)
+    m.factories[module.Prompt()] = factory
+}
+
+// Spawn returns a new instance of a registered module carrying a copy of the current
+// options of the registered one, so that it can run independently of it.
+// ok is false when the module is unknown or was registered without a factory.
+func (m *ModuleManager) Spawn(name string) (Module, bool) {
+    factory, ok := m.factories[name]
+    if !ok {
+        return nil, false
+    }
+    module := factory()
+    if r, ok := module.(findings.Reporter); ok {
+        r.SetFindings(m.findings)
+    }
+
+    src, srcOk := m.modules[name].(Configurable)
+    dst, dstOk := module.(Configurable)
+    if srcOk && dstOk {
+        dst.OptionManager().CopyValues(src.OptionManager())
+    }
+    return module, true
+}
+
+// Reset brings the registered modules back to their initial state, keeping the
+// instances: options return to their defaults, results of Persistent modules and
+// the shared findings are cleared.
+func (m *ModuleManager) Reset() {
+    m.findings.Reset()
+    for _, module := range m.modules {
+        if c, ok := module.(Configurable); ok {
+            c.OptionManager().Reset()
+        }
+        if p, ok := module.(Persistent); ok {
+            p.Import([]byte("null")) // Every module decodes null as no results
+        }
+    }
+}
+
+// Findings returns the store where registered modules record their findings.
+func (m *ModuleManager) Findings() *findings.Store {
+    return m.findings
+}
+
 // Get retrieves a registered module by name.
 func (m *ModuleManager) Get(name string) (Module, bool) {
     mod, ok := m.modules[name]
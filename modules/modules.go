@@ -3,6 +3,8 @@ package modules
 import (
     "fmt"
     "context"
+    "os"
+    "strings"
 
     "github.com/czz/oblivion/modules/dnsbrute"
     "github.com/czz/oblivion/modules/fuzzer"
@@ -10,6 +12,10 @@ import (
     "github.com/czz/oblivion/modules/subdomains_search"
     "github.com/czz/oblivion/modules/subdomain_takeover"
     "github.com/czz/oblivion/modules/webspider"
+    "github.com/czz/oblivion/utils/diff"
+    "github.com/czz/oblivion/utils/export"
+    "github.com/czz/oblivion/utils/findings"
+    "github.com/czz/oblivion/utils/option"
 )
 
 // Module defines the methods that every module should implement
@@ -29,19 +35,117 @@ type Module interface {
     Results() [][]string
 }
 
+// Persistent is implemented by modules that can export their results and restore
+// them later, e.g. when a workspace is reopened.
+type Persistent interface {
+    Export() ([]byte, error)  // Serialize the current results
+    Import([]byte) error      // Restore results produced by Export
+}
+
+// Configurable is implemented by modules exposing their OptionManager.
+type Configurable interface {
+    OptionManager() *option.OptionManager
+}
+
+// Tabular is implemented by modules whose results have named columns, used by show
+// to sort, filter and select columns by name.
+type Tabular interface {
+    Headers() []string
+}
+
+// Headers returns the column names of the results of a module; modules that do
+// not implement Tabular get none.
+func Headers(m Module) []string {
+    if t, ok := m.(Tabular); ok {
+        return t.Headers()
+    }
+    return nil
+}
+
+// Comparable is implemented by modules telling diff how to match the rows of two
+// runs: the key columns identifying a row and the columns that change on every run
+// (e.g. response times) and are not compared.
+type Comparable interface {
+    DiffColumns() (keys, ignore []string)
+}
+
+// DiffColumns returns the key and ignored columns of a module; modules that do not
+// implement Comparable are compared on whole rows.
+func DiffColumns(m Module) (keys, ignore []string) {
+    if c, ok := m.(Comparable); ok {
+        return c.DiffColumns()
+    }
+    return nil, nil
+}
+
+// Compare returns the differences between two runs of a module, matching rows by
+// its DiffColumns.
+func Compare(m Module, before, after [][]string) (*diff.Diff, error) {
+    keys, ignore := DiffColumns(m)
+    d, err := diff.Compare(Headers(m), keys, ignore, before, after)
+    if err != nil {
+        return nil, err
+    }
+    d.Module = m.Prompt()
+    return d, nil
+}
+
+// Structured is implemented by modules keeping richer results than their table,
+// written as they are by the json and jsonl export formats.
+type Structured interface {
+    Records() []interface{}
+}
+
+// Finder is implemented by modules whose results are security findings, written by
+// the sarif and findings export formats.
+type Finder interface {
+    Vulnerabilities() []findings.Vulnerability
+}
+
+// ExportData returns the results of a module, with their headers, structured
+// records and findings when the module has them, ready for utils/export.
+func ExportData(m Module) export.Data {
+    d := export.Data{Name: m.Prompt(), Headers: Headers(m), Rows: m.Results()}
+    if s, ok := m.(Structured); ok {
+        d.Records = s.Records()
+    }
+    if f, ok := m.(Finder); ok {
+        d.Findings = f.Vulnerabilities()
+    }
+    return d
+}
+
+// Validate checks the options of a module before it is run. Modules that do not
+// expose their OptionManager are not checked.
+func Validate(m Module) []error {
+    if c, ok := m.(Configurable); ok {
+        return c.OptionManager().Validate()
+    }
+    return nil
+}
+
+// Factory creates a new, independent instance of a module.
+type Factory func() Module
+
+// IsErrorResult reports whether a module returned its error row instead of results.
+// Modules signal failures with a single row whose first cell starts with "Error".
+func IsErrorResult(results [][]string) bool {
+    return len(results) == 1 && len(results[0]) > 0 && strings.HasPrefix(results[0][0], "Error")
+}
+
 // LoadModules loads all available modules and returns them in a slice
 func LoadModules() *ModuleManager {
 
     manager := NewModuleManager()
-    manager.Register(dnsbrute.NewDNSBrute())
-    manager.Register(fuzzer.NewFuzzer())
-    manager.Register(portscanner.NewPortScanner())
-    manager.Register(subdomains_search.NewSubdomainsSearch())
-    manager.Register(subdomain_takeover.NewSubdomainTakeover())
-    manager.Register(webspider.NewWebSpider())
-
-    // Log the number of modules loaded
-    fmt.Printf("Loaded %d modules\n\n", len(manager.List()))
+    manager.RegisterFactory(func() Module { return dnsbrute.NewDNSBrute() })
+    manager.RegisterFactory(func() Module { return fuzzer.NewFuzzer() })
+    manager.RegisterFactory(func() Module { return portscanner.NewPortScanner() })
+    manager.RegisterFactory(func() Module { return subdomains_search.NewSubdomainsSearch() })
+    manager.RegisterFactory(func() Module { return subdomain_takeover.NewSubdomainTakeover() })
+    manager.RegisterFactory(func() Module { return webspider.NewWebSpider() })
+
+    // Log the number of modules loaded (stderr keeps stdout clean for piped output)
+    fmt.Fprintf(os.Stderr, "Loaded %d modules\n\n", len(manager.List()))
 
     return manager
 }
@@ -10,11 +10,15 @@ import (
     "sync"
     "time"
     "net/http"
-    "sort"
     "context"
 
     "github.com/go-ping/ping"
+    "github.com/czz/oblivion/utils/findings"
     "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/progress"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/stream"
+    "github.com/czz/oblivion/utils/target"
     "github.com/czz/oblivion/utils/help"
 )
 
@@ -36,18 +40,20 @@ type PortScanner struct {
     results     [][]string
     jsonResults []JsonScanResult
     Client      *http.Client
+    findings    *findings.Store
+    progress    progress.Counter
 }
 
 func NewPortScanner() *PortScanner {
     om := option.NewOptionManager()
 
-    om.Register(option.NewOption("TARGETS", []string{}, true, "Targets"))
-    om.Register(option.NewOption("PORTS", []int{}, true, "Ports to scan"))
+    om.Register(option.NewOption("TARGETS", []string{}, true, "Targets to scan").WithType(option.TypeTargets))
+    om.Register(option.NewOption("PORTS", []int{}, true, "Ports to scan").WithExample("80 or 22,80 or 1-10000"))
     om.Register(option.NewOption("TIMEOUT", 1, false, "Timeout in seconds"))
     om.Register(option.NewOption("THREADS", 200, false, "Number of threads"))
     om.Register(option.NewOption("ENABLE_ICMP", false, false, "Enable ICMP"))
     om.Register(option.NewOption("ENABLE_UDP", false, false, "Enable UDP scan"))
-    om.Register(option.NewOption("RATE_LIMIT", 100, false, "Rate Limit"))
+    om.Register(option.NewOption("RATE_LIMIT", 100, false, "Maximum connections per second of each thread, 0 for no limit"))
 
     var netTransport = &http.Transport{
         Dial: (&net.Dialer{
@@ -61,15 +67,7 @@ func NewPortScanner() *PortScanner {
     }
 
     helpManager := help.NewHelpManager()
-    helpManager.Register("portscanner", "Portscanner module",[][]string{
-  		{"TARGETS", "example.com or abc.com,def.com or /pathtofile.txt", "Targets to scan"},
-  		{"PORTS", "80 or 22,80 or 1-10000", "Ports to scan"},
-  		{"TIMEOUT", "1", "Timeout in seconds"},
-      {"THREADS", "200", "Number of threads"},
-      {"ENABLE_ICMP", "true or false", "Enable ICMP"},
-      {"ENABLE_UDP", "true or false", "Enable UDP scan"},
-      {"RATE_LIMIT", "100", "Rate Limit"},
-  	})
+    helpManager.RegisterOptions("portscanner", "Portscanner module", om)
 
     return &PortScanner{
         optionManager:    om,
@@ -83,31 +81,31 @@ func NewPortScanner() *PortScanner {
 }
 
 func (p *PortScanner) Run(ctx context.Context) [][]string {
-    var targets []string
-    var threads int
-
     // Recupera TARGETS e THREADS dalle opzioni
-    if val, ok := p.optionManager.Get("TARGETS"); ok {
-        if ts, ok := val.Value.([]string); ok {
-            targets = ts
-        }
-    }
-    if val, ok := p.optionManager.Get("THREADS"); ok {
-        if th, ok := val.Value.(int); ok {
-            threads = th
+    targets := p.optionManager.Strings("TARGETS")
+    threads := p.optionManager.Int("THREADS")
+
+    // CIDRs and ranges are already expanded by the option; URLs are scanned by host
+    var ips []string
+    seen := make(map[string]bool)
+    for _, t := range targets {
+        if host := target.Host(t); !seen[host] && scope.Allowed(ctx, host) {
+            seen[host] = true
+            ips = append(ips, host)
         }
     }
+    ports := p.optionManager.Ints("PORTS")
+    p.progress.Start(len(ips) * len(ports))
+    defer p.progress.Finish()
 
     // Prepara canali e WaitGroup
-    tasks := make(chan string, len(targets))
-    results := make(chan JsonScanResult, len(targets))
+    tasks := make(chan string, len(ips))
+    results := make(chan JsonScanResult, len(ips))
     var wg sync.WaitGroup
 
     // Popola il canale tasks
-    for _, target := range targets {
-        for _, ip := range expandCIDR(target) {
-            tasks <- ip
-        }
+    for _, ip := range ips {
+        tasks <- ip
     }
     close(tasks)
 
@@ -125,8 +123,7 @@ func (p *PortScanner) Run(ctx context.Context) [][]string {
                     if !more {
                         return
                     }
-                    // Esegui scanTarget (potresti far passare il ctx in scanTarget per usarlo nelle richieste)
-                    result := p.scanTarget(ip)
+                    result := p.scanTarget(ctx, ip)
                     // Invia il risultato solo se il contesto non è stato cancellato
                     select {
                     case <-ctx.Done():
@@ -150,6 +147,7 @@ func (p *PortScanner) Run(ctx context.Context) [][]string {
         select {
         case <-ctx.Done():
             // Se è arrivata la cancellazione, usciamo dal loop
+            p.results = tableData
             return tableData
         case res, more := <-results:
             if !more {
@@ -163,31 +161,19 @@ func (p *PortScanner) Run(ctx context.Context) [][]string {
                 proto := res.Protocol[port]
                 row := []string{res.IP, fmt.Sprintf("%d/%s", port, proto), banner}
                 tableData = append(tableData, row)
+                stream.Emit(ctx, row)
+                p.findings.AddService(res.IP, port, proto, banner, p.prompt)
             }
         }
     }
 }
 
-func (p *PortScanner) scanTarget(ip string) JsonScanResult {
-    var enICMP, enUDP bool
-    var timeout, threadCount int
-    var ports []int
-
-    if val, ok := p.optionManager.Get("ENABLE_ICMP"); ok {
-        enICMP, _ = val.Value.(bool)
-    }
-    if val, ok := p.optionManager.Get("ENABLE_UDP"); ok {
-        enUDP, _ = val.Value.(bool)
-    }
-    if val, ok := p.optionManager.Get("TIMEOUT"); ok {
-        timeout, _ = val.Value.(int)
-    }
-    if val, ok := p.optionManager.Get("THREADS"); ok {
-        threadCount, _ = val.Value.(int)
-    }
-    if val, ok := p.optionManager.Get("PORTS"); ok {
-        ports, _ = val.Value.([]int)
-    }
+func (p *PortScanner) scanTarget(ctx context.Context, ip string) JsonScanResult {
+    enICMP := p.optionManager.Bool("ENABLE_ICMP")
+    enUDP := p.optionManager.Bool("ENABLE_UDP")
+    timeout := p.optionManager.Int("TIMEOUT")
+    threadCount := p.optionManager.Int("THREADS")
+    ports := p.optionManager.Ints("PORTS")
 
     result := JsonScanResult{
         IP:       ip,
@@ -225,10 +211,7 @@ func (p *PortScanner) scanTarget(ip string) JsonScanResult {
 
     var wg sync.WaitGroup
 
-    rateLimit := 0
-    if val, ok := p.optionManager.Get("RATE_LIMIT"); ok {
-        rateLimit, _ = val.Value.(int)
-    }
+    rateLimit := p.optionManager.Int("RATE_LIMIT")
     var delay time.Duration
     if rateLimit > 0 {
         delay = time.Second / time.Duration(rateLimit)
@@ -242,6 +225,7 @@ func (p *PortScanner) scanTarget(ip string) JsonScanResult {
                 if delay > 0 {
                     time.Sleep(delay)
                 }
+                p.progress.Inc()
 
                 address := net.JoinHostPort(ip, strconv.Itoa(port))
 
@@ -250,7 +234,7 @@ func (p *PortScanner) scanTarget(ip string) JsonScanResult {
                     banner := getBanner(conn)
                     conn.Close()
                     if banner == "" {
-                        banner = httpGrabBanner(p.Client, ip, port)
+                        banner = httpGrabBanner(ctx, p.Client, ip, port)
                     }
                     output <- scanResult{port, banner, "tcp"}
                 }
@@ -305,9 +289,17 @@ func getBanner(conn net.Conn) string {
     return strings.TrimSpace(string(buf[:n]))
 }
 
-func httpGrabBanner(client *http.Client, ip string, port int) string {
-    address := fmt.Sprintf("http://%s:%d", ip, port)
-    resp, err := client.Get(address)
+// httpGrabBanner returns the HTTP status of a port, following only the redirects
+// in the scope of ctx.
+func httpGrabBanner(ctx context.Context, client *http.Client, ip string, port int) string {
+    address := "http://" + net.JoinHostPort(ip, strconv.Itoa(port))
+    scoped := *client
+    scoped.CheckRedirect = scope.CheckRedirect(ctx)
+    req, err := http.NewRequestWithContext(ctx, "GET", address, nil)
+    if err != nil {
+        return ""
+    }
+    resp, err := scoped.Do(req)
     if err != nil {
         return ""
     }
@@ -315,39 +307,6 @@ func httpGrabBanner(client *http.Client, ip string, port int) string {
     return fmt.Sprintf("HTTP %d %s", resp.StatusCode, resp.Status)
 }
 
-func expandCIDR(input string) []string {
-    var results []string
-    if strings.Contains(input, "/") {
-        ip, ipnet, err := net.ParseCIDR(input)
-        if err != nil {
-            return []string{input}
-        }
-        ip = ip.To4()
-        if ip == nil {
-            return []string{input}
-        }
-        for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); ip = nextIP(ip) {
-            ipStr := ip.String()
-            if ipStr != "127.0.0.1" && !strings.HasPrefix(ipStr, "169.254") {
-                results = append(results, ipStr)
-            }
-        }
-        return results
-    }
-    return []string{input}
-}
-
-func nextIP(ip net.IP) net.IP {
-    ip = ip.To4()
-    for j := len(ip) - 1; j >= 0; j-- {
-        ip[j]++
-        if ip[j] != 0 {
-            break
-        }
-    }
-    return ip
-}
-
 func (p *PortScanner) saveJSON(filename string) error {
     file, err := os.Create(filename)
     if err != nil {
@@ -360,138 +319,43 @@ func (p *PortScanner) saveJSON(filename string) error {
     return encoder.Encode(p.jsonResults)
 }
 
-func parsePorts(portStr string) []int {
-    var ports []int
-    seen := make(map[int]bool)
-    for _, part := range strings.Split(portStr, ",") {
-        part = strings.TrimSpace(part)
-        if strings.Contains(part, "-") {
-            bounds := strings.Split(part, "-")
-            if len(bounds) != 2 {
-                continue
-            }
-            start, _ := strconv.Atoi(bounds[0])
-            end, _ := strconv.Atoi(bounds[1])
-            for i := start; i <= end; i++ {
-                if !seen[i] {
-                    ports = append(ports, i)
-                    seen[i] = true
-                }
-            }
-        } else {
-            p, err := strconv.Atoi(part)
-            if err == nil && !seen[p] {
-                ports = append(ports, p)
-                seen[p] = true
-            }
-        }
-    }
-    return ports
+func (p *PortScanner) Save(filename string) error {
+    return p.saveJSON(filename)
 }
 
-func compressPorts(nums []int) string {
-    if len(nums) == 0 {
-        return ""
-    }
-    sort.Ints(nums)
-    var result []string
-    start := nums[0]
-    end := nums[0]
-    for i := 1; i < len(nums); i++ {
-        if nums[i] == end+1 {
-            end = nums[i]
-        } else {
-            if start == end {
-                result = append(result, fmt.Sprintf("%d", start))
-            } else {
-                result = append(result, fmt.Sprintf("%d-%d", start, end))
-            }
-            start = nums[i]
-            end = nums[i]
-        }
-    }
-    if start == end {
-        result = append(result, fmt.Sprintf("%d", start))
-    } else {
-        result = append(result, fmt.Sprintf("%d-%d", start, end))
-    }
-    return strings.Join(result, ",")
+// scanState is the serialized form of the scanner results used by Export/Import.
+type scanState struct {
+    Table [][]string       `json:"table"`
+    Scans []JsonScanResult `json:"scans"`
 }
 
-func (p *PortScanner) Save(filename string) error {
-    return p.saveJSON(filename)
+// Export serializes the scan results.
+func (p *PortScanner) Export() ([]byte, error) {
+    return json.Marshal(scanState{Table: p.results, Scans: p.jsonResults})
+}
+
+// Import restores scan results produced by Export.
+func (p *PortScanner) Import(data []byte) error {
+    var st scanState
+    if err := json.Unmarshal(data, &st); err != nil {
+        return err
+    }
+    p.results = st.Table
+    p.jsonResults = st.Scans
+    return nil
 }
 
 func (p *PortScanner) Options() []map[string]string {
     res := make([]map[string]string, 0, len(p.optionManager.List()))
     for _, opt := range p.optionManager.List() {
-        switch opt.Name {
-        case "PORTS":
-            if val, ok := opt.Value.([]int); ok {
-                ports := map[string]string{"name": opt.Name, "value": compressPorts(val), "required": "true", "description": opt.Description}
-                res = append(res, ports)
-            }
-        default:
-            res = append(res, opt.Format())
-        }
+        res = append(res, opt.Format())
     }
     return res
 }
 
+// Set parses and sets an option.
 func (p *PortScanner) Set(n string, v string) []string {
-
-    opt, ok := p.optionManager.Get(n)
-    if ok {
-        switch opt.Name {
-            case "TARGETS":
-                var targets []string
-                v = strings.TrimSpace(v)
-                if fileInfo, err := os.Stat(v); err == nil && !fileInfo.IsDir() {
-                    content, err := os.ReadFile(v)
-                    if err != nil {
-                        return []string{"TARGETS", "Error reading file"}
-                    }
-                    lines := strings.Split(string(content), "\n")
-                    for _, line := range lines {
-                        line = strings.TrimSpace(line)
-                        if line != "" {
-                            targets = append(targets, line)
-                        }
-                    }
-                } else {
-                    for _, t := range strings.Split(v, ",") {
-                        t = strings.TrimSpace(t)
-                        if t != "" {
-                            targets = append(targets, t)
-                        }
-                    }
-                }
-                opt.Set(targets)
-                return []string{opt.Name, fmt.Sprint(targets)}
-            case "PORTS":
-                ports := parsePorts(v)
-                opt.Set(ports)
-                return []string{opt.Name, fmt.Sprint(v)}
-            case "TIMEOUT", "RATE_LIMIT", "THREADS":
-                if intVal, err := strconv.Atoi(v); err == nil {
-                    opt.Set(intVal)
-                    return []string{opt.Name, fmt.Sprint(intVal)}
-                } else {
-                    return []string{opt.Name, "Invalid integer value"}
-                }
-            case "ENABLE_UDP", "ENABLE_ICMP":
-                if v == "true" {
-                    opt.Set(true)
-                } else {
-                    opt.Set(false)
-                }
-                return []string{opt.Name, fmt.Sprint(opt.Value)}
-            default:
-                opt.Set(v)
-                return []string{opt.Name, fmt.Sprint(v)}
-            }
-        }
-    return []string{"Error", "Option not found"}
+    return p.optionManager.Apply(n, v)
 }
 
 func (p *PortScanner) Help() [][]string {
@@ -500,10 +364,45 @@ func (p *PortScanner) Help() [][]string {
 
 }
 
+// SetFindings connects the scanner to the shared findings store.
+func (p *PortScanner) SetFindings(store *findings.Store) {
+    p.findings = store
+}
+
+// Progress returns the number of ports probed so far.
+func (p *PortScanner) Progress() progress.Status {
+    return p.progress.Status()
+}
+
+// OptionManager returns the options of the scanner.
+func (p *PortScanner) OptionManager() *option.OptionManager {
+    return p.optionManager
+}
+
 func (p *PortScanner) Results() [][]string {
     return p.results
 }
 
+// Records returns the scan results of every host, exported by the json formats.
+func (p *PortScanner) Records() []interface{} {
+    out := make([]interface{}, len(p.jsonResults))
+    for i, r := range p.jsonResults {
+        out[i] = r
+    }
+    return out
+}
+
+// Headers returns the names of the result columns.
+func (p *PortScanner) Headers() []string {
+    return []string{"HOST", "PORT", "BANNER"}
+}
+
+// DiffColumns matches the results of two runs by host and port, so a different
+// banner shows as a change.
+func (p *PortScanner) DiffColumns() (keys, ignore []string) {
+    return []string{"HOST", "PORT"}, nil
+}
+
 func (s *PortScanner) Name() string       { return s.name }
 func (s *PortScanner) Author() string     { return s.author }
 func (s *PortScanner) Description() string { return s.desc }
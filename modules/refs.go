@@ -0,0 +1,125 @@
+package modules
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// ResultsLookup returns the results known under a reference name.
+type ResultsLookup func(name string) ([][]string, bool)
+
+// ExpandRefs replaces references to other results inside a comma-separated value.
+//
+// An item "@name" or "@name.results" expands to the first column of every result row
+// of name, "@name.results.N" (or "@name.N") to column N (0-based). A value without
+// any item referencing a name known to lookup is returned untouched, so e-mail
+// addresses, headers or regexps starting with "@" are never rewritten. Otherwise
+// other items, including "@word" items naming nothing known, are kept as they are,
+// a leading "@@" escapes a literal "@", empty and duplicate values are dropped, and
+// the items are joined again with commas so that the file-or-comma-list loaders of
+// the modules can consume them.
+func ExpandRefs(value string, lookup ResultsLookup) (string, error) {
+    if !strings.Contains(value, "@") {
+        return value, nil
+    }
+    items := strings.Split(value, ",")
+    found := false
+    for _, item := range items {
+        if isRef(strings.TrimSpace(item), lookup) {
+            found = true
+            break
+        }
+    }
+    if !found {
+        return value, nil
+    }
+
+    var out []string
+    seen := make(map[string]bool)
+    add := func(v string) {
+        v = strings.TrimSpace(v)
+        if v != "" && !seen[v] {
+            seen[v] = true
+            out = append(out, v)
+        }
+    }
+
+    for _, item := range items {
+        item = strings.TrimSpace(item)
+        switch {
+        case strings.HasPrefix(item, "@@"):
+            add(item[1:])
+        case isRef(item, lookup):
+            values, err := resolveRef(item[1:], lookup)
+            if err != nil {
+                return "", err
+            }
+            for _, v := range values {
+                add(v)
+            }
+        default:
+            add(item)
+        }
+    }
+    return strings.Join(out, ","), nil
+}
+
+// isRef reports whether an item is a reference to a name known to lookup.
+func isRef(item string, lookup ResultsLookup) bool {
+    if !strings.HasPrefix(item, "@") || strings.HasPrefix(item, "@@") {
+        return false
+    }
+    _, ok := lookup(strings.SplitN(item[1:], ".", 2)[0])
+    return ok
+}
+
+// resolveRef returns the values selected by a single reference (without the '@').
+func resolveRef(ref string, lookup ResultsLookup) ([]string, error) {
+    parts := strings.Split(ref, ".")
+    name := parts[0]
+    column := 0
+
+    rest := parts[1:]
+    if len(rest) > 0 && rest[0] == "results" {
+        rest = rest[1:]
+    }
+    if len(rest) > 1 {
+        return nil, fmt.Errorf("invalid reference @%s", ref)
+    }
+    if len(rest) == 1 {
+        n, err := strconv.Atoi(rest[0])
+        if err != nil || n < 0 {
+            return nil, fmt.Errorf("invalid column %q in reference @%s", rest[0], ref)
+        }
+        column = n
+    }
+
+    rows, ok := lookup(name)
+    if !ok {
+        return nil, fmt.Errorf("unknown reference @%s", name)
+    }
+    if len(rows) == 0 {
+        return nil, fmt.Errorf("@%s has no results yet", name)
+    }
+
+    var values []string
+    for _, row := range rows {
+        if column < len(row) {
+            values = append(values, row[column])
+        }
+    }
+    return values, nil
+}
+
+// ResolveRefs expands @module references in value using the current results of the
+// registered modules.
+func (m *ModuleManager) ResolveRefs(value string) (string, error) {
+    return ExpandRefs(value, func(name string) ([][]string, bool) {
+        module, ok := m.Get(name)
+        if !ok {
+            return nil, false
+        }
+        return module.Results(), true
+    })
+}
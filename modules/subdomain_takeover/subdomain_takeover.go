@@ -1,7 +1,8 @@
 package subdomain_takeover
 
 import (
-    "fmt"
+    "encoding/csv"
+    "encoding/json"
     "io"
     "net"
     "net/http"
@@ -11,8 +12,13 @@ import (
     "time"
     "context"
 
+    "github.com/czz/oblivion/utils/findings"
     "github.com/czz/oblivion/utils/help"
     "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/progress"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/stream"
+    "github.com/czz/oblivion/utils/target"
 )
 
 type Service struct {
@@ -32,6 +38,8 @@ type SubdomainTakeover struct {
     prompt  string
     help    *help.HelpManager
     results [][]string
+    findings *findings.Store
+    progress progress.Counter
 }
 
 
@@ -40,21 +48,13 @@ const (
 	Timeout        = 10 * time.Second
 )
 
-var httpClient = &http.Client{
-	Timeout: Timeout,
-}
-
-
-
 func NewSubdomainTakeover() *SubdomainTakeover {
     om := option.NewOptionManager()
 
-    om.Register(option.NewOption("DOMAINS", "", true, "example.com or abc.com,def.com or /pathtofile.txt"))
+    om.Register(option.NewOption("DOMAINS", []string{}, true, "Domains to check for takeover").WithType(option.TypeTargets).WithExample("example.com or abc.com,def.com or /pathtofile.txt"))
 
     hm := help.NewHelpManager()
-    hm.Register("subdomain_takeover", "Detect subdomain takeover by matching CNAME and response signature", [][]string{
-        {"DOMAINS", "domains.txt", "example.com or abc.com,def.com or /pathtofile.txt"},
-    })
+    hm.RegisterOptions("subdomain_takeover", "Detect subdomain takeover by matching CNAME and response signature", om)
 
     return &SubdomainTakeover{
         optionManager: om,
@@ -69,25 +69,29 @@ func NewSubdomainTakeover() *SubdomainTakeover {
 func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
     // Prepara la lista di domini
     var domains []string
-    if val, ok := s.optionManager.Get("DOMAINS"); ok {
-        if vs, ok := val.Value.([]string); ok {
-            domains = vs
+    for _, t := range s.optionManager.Strings("DOMAINS") {
+        if host := target.Host(t); scope.Allowed(ctx, host) {
+            domains = append(domains, host)
         }
     }
 
+    s.progress.Start(len(domains))
+    defer s.progress.Finish()
+
     sem := make(chan struct{}, MaxConcurrency)
     var wg sync.WaitGroup
 
     resultsCh := make(chan []string)
-    var mu sync.Mutex
     var results [][]string
 
-    // Collector
+    // Collector; collected is closed once every row is recorded and emitted
+    collected := make(chan struct{})
     go func() {
+        defer close(collected)
         for rec := range resultsCh {
-            mu.Lock()
             results = append(results, rec)
-            mu.Unlock()
+            stream.Emit(ctx, rec)
+            s.report(rec)
         }
     }()
 
@@ -109,6 +113,7 @@ func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
         go func(d string) {
             defer wg.Done()
             defer func() { <-sem }()
+            defer s.progress.Inc()
 
             // Rispetta la cancellazione
             select {
@@ -117,7 +122,10 @@ func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
             default:
             }
 
-            cname, err := net.LookupCNAME(d)
+            cname, err := net.DefaultResolver.LookupCNAME(ctx, d)
+            if ctx.Err() != nil {
+                return // A cancelled lookup is not an NXDOMAIN
+            }
             if err != nil {
                 select {
                 case resultsCh <- []string{d, "", "", "NXDOMAIN", "false"}:
@@ -142,7 +150,7 @@ func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
                         // NXDOMAIN fingerprint
                         for _, fp := range svc.Fingerprint {
                             if fp == "NXDOMAIN" {
-                                if _, err := net.LookupHost(cname); err != nil {
+                                if _, err := net.DefaultResolver.LookupHost(ctx, cname); err != nil && ctx.Err() == nil {
                                     select {
                                     case resultsCh <- []string{d, cname, svc.Service, "Vulnerable", "true"}:
                                     case <-ctx.Done():
@@ -153,7 +161,7 @@ func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
                         }
 
                         // HTTP fingerprint con contesto
-                        client := &http.Client{Timeout: 10 * time.Second}
+                        client := &http.Client{Timeout: Timeout, CheckRedirect: scope.CheckRedirect(ctx)}
                         for _, scheme := range []string{"http://", "https://"} {
                             req, err := http.NewRequestWithContext(ctx, "GET", scheme+d, nil)
                             if err != nil {
@@ -183,94 +191,112 @@ func (s *SubdomainTakeover) Run(ctx context.Context) [][]string {
         }(domain)
     }
 
-    // Chiude resultsCh solo quando tutti i worker hanno finito
-    go func() {
-        wg.Wait()
-        close(resultsCh)
-    }()
-
-    // Attende che la cancellazione o il termine dei worker
-    select {
-    case <-ctx.Done():
-        // qui possiamo loggare o pulire
-    case <-func() chan struct{} {
-        done := make(chan struct{})
-        go func() {
-            wg.Wait()
-            close(done)
-        }()
-        return done
-    }():
-    }
+    // Workers return on cancellation, so waiting for them keeps the rows found so far
+    wg.Wait()
+    close(resultsCh)
+    <-collected
 
     s.results = results
-    if len(results) == 0 {
+    if len(s.results) == 0 {
         return nil
     }
     return s.results
 }
 
+// report records a vulnerable result row as a finding.
+func (s *SubdomainTakeover) report(rec []string) {
+    if v, ok := s.vulnerability(rec); ok {
+        s.findings.AddVulnerability(v)
+    }
+}
+
+// vulnerability returns the finding of a result row (domain, cname, service, status,
+// vulnerable), if the domain is vulnerable.
+func (s *SubdomainTakeover) vulnerability(rec []string) (findings.Vulnerability, bool) {
+    if len(rec) < 5 || rec[4] != "true" {
+        return findings.Vulnerability{}, false
+    }
+    title := "Subdomain takeover (" + rec[2] + ")"
+    return findings.Vulnerability{
+        ID:       findings.VulnerabilityID(s.prompt, title, rec[0]),
+        Title:    title,
+        Severity: "high",
+        Target:   rec[0],
+        Evidence: "CNAME " + rec[1] + " points to an unclaimed " + rec[2] + " resource",
+        Module:   s.prompt,
+    }, true
+}
+
+// Vulnerabilities returns the vulnerable domains as findings, with the time they
+// were first reported to the findings store.
+func (s *SubdomainTakeover) Vulnerabilities() []findings.Vulnerability {
+    var out []findings.Vulnerability
+    for _, rec := range s.results {
+        v, ok := s.vulnerability(rec)
+        if !ok {
+            continue
+        }
+        if known, ok := s.findings.Vulnerability(v.ID); ok {
+            v.Timestamp = known.Timestamp
+        } else {
+            v.Timestamp = time.Now()
+        }
+        out = append(out, v)
+    }
+    return out
+}
+
+// SetFindings connects the module to the shared findings store.
+func (s *SubdomainTakeover) SetFindings(store *findings.Store) {
+    s.findings = store
+}
+
+// Progress returns the number of domains checked so far.
+func (s *SubdomainTakeover) Progress() progress.Status {
+    return s.progress.Status()
+}
+
+// OptionManager returns the options of the module.
+func (s *SubdomainTakeover) OptionManager() *option.OptionManager {
+    return s.optionManager
+}
+
 func (s *SubdomainTakeover) Save(path string) error {
     f, err := os.Create(path)
     if err != nil {
         return err
     }
     defer f.Close()
-    for _, line := range s.results {
-        f.WriteString(strings.Join(line, ",") + "\n")
-    }
-    return nil
+    // Quote cells containing commas or quotes
+    return csv.NewWriter(f).WriteAll(s.results)
 }
 
-func (s *SubdomainTakeover) Set(n, v string) []string {
-    om := *s.optionManager
-    op, ok := om.Get(n)
-
-    if ok {
-        if op.Name == "DOMAINS" {
-            var targets []string
-            v = strings.TrimSpace(v)
-            if fileInfo, err := os.Stat(v); err == nil && !fileInfo.IsDir() {
-                content, err := os.ReadFile(v)
-                if err != nil {
-                    return []string{"DOMAINS", "Error reading file"}
-                }
-                lines := strings.Split(string(content), "\n")
-                for _, line := range lines {
-                    line = strings.TrimSpace(line)
-                    if line != "" {
-                        //if !checkURL(line) { // regez per vedere se è un sotodominio o dominio
-                        //    return []string{"DOMAINS", "Error: url must start with http:// or https:// check your file"}
-                        //}
-                        targets = append(targets, line)
-                    }
-                }
-            } else {
-                for _, t := range strings.Split(v, ",") {
-                    t = strings.TrimSpace(t)
-                    if t != "" {
-                        //if !checkURL(t) {
-                        //   return []string{"TARGETS", "Error: url must start with http:// or https:// check your file"}
-                        //}
-                        targets = append(targets, t)
-                    }
-                }
-            }
-            op.Set(targets)
-            return []string{n, fmt.Sprint(targets)}
-        }
+// Export serializes the takeover results.
+func (s *SubdomainTakeover) Export() ([]byte, error) {
+    return json.Marshal(s.results)
+}
 
-        op.Set(v)
-        return []string{op.Name, fmt.Sprintf("%v", op.Value)}
+// Import restores takeover results produced by Export.
+func (s *SubdomainTakeover) Import(data []byte) error {
+    var results [][]string
+    if err := json.Unmarshal(data, &results); err != nil {
+        return err
     }
-
+    s.results = results
     return nil
 }
 
+// Set parses and sets an option.
+func (s *SubdomainTakeover) Set(n, v string) []string {
+    return s.optionManager.Apply(n, v)
+}
+
 
 func (s *SubdomainTakeover) Help() [][]string            { help, _ := s.help.Get(s.prompt); return help }
 func (s *SubdomainTakeover) Options() []map[string]string { opt := make([]map[string]string, len(s.optionManager.List())); for i, v := range s.optionManager.List() { opt[i] = v.Format() }; return opt }
 func (s *SubdomainTakeover) Results() [][]string         { return s.results }
+func (s *SubdomainTakeover) Headers() []string           { return []string{"DOMAIN", "CNAME", "SERVICE", "STATUS", "VULNERABLE"} }
+func (s *SubdomainTakeover) DiffColumns() (keys, ignore []string) { return []string{"DOMAIN"}, nil }
 func (s *SubdomainTakeover) Name() string                { return s.name }
 func (s *SubdomainTakeover) Author() string              { return s.author }
 func (s *SubdomainTakeover) Description() string         { return s.desc }
@@ -13,7 +13,12 @@ import (
 	"time"
 	"context"
 
+	"github.com/czz/oblivion/utils/findings"
 	"github.com/czz/oblivion/utils/option"
+	"github.com/czz/oblivion/utils/progress"
+	"github.com/czz/oblivion/utils/scope"
+	"github.com/czz/oblivion/utils/stream"
+	"github.com/czz/oblivion/utils/target"
 	"github.com/czz/oblivion/utils/help"
 )
 
@@ -42,14 +47,21 @@ type SubdomainsSearch struct {
 	prompt        string                // CLI prompt name
 	help          *help.HelpManager     // Help manager for CLI usage
 	results       []string              // Stores the found subdomains
+	findings      *findings.Store       // Shared findings store
+	progress      progress.Counter      // Sources queried in the current run
 }
 
-// fetchSubdomains queries a given source URL and extracts subdomains from the response
-func (s *SubdomainsSearch) fetchSubdomains(url string, domain string) ([]string, error) {
+// fetchSubdomains queries a given source URL and extracts subdomains from the response;
+// the request is abandoned when ctx is cancelled
+func (s *SubdomainsSearch) fetchSubdomains(ctx context.Context, url string, domain string) ([]string, error) {
 	formattedURL := fmt.Sprintf(url, domain)
 	client := &http.Client{Timeout: 10 * time.Second}
 
-	resp, err := client.Get(formattedURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", formattedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("Error fetching from %s: %v", formattedURL, err)
 		return nil, err
@@ -174,16 +186,14 @@ func filterByDomain(subdomains []string, domain string) []string {
 func NewSubdomainsSearch() *SubdomainsSearch {
 	om := option.NewOptionManager()
 
-	om.Register(option.NewOption("SOURCES_URI", defaultSources, true, "Sources to retrieve subdomains (all free sources)"))
-	om.Register(option.NewOption("DOMAIN", "", true, "Domain to search subdomains"))
+	om.Register(option.NewOption("SOURCES_URI", defaultSources, true, "Sources to retrieve subdomains (all free sources)").
+		WithExample("read-only").
+		WithDoc("Free APIs queried for every domain; %s is replaced by the domain.\nThe list is fixed and cannot be changed with set."))
+	om.Register(option.NewOption("DOMAIN", []string{}, true, "Domains to search subdomains").WithType(option.TypeTargets).WithExample("example.com or abc.com,def.com or /pathtofile.txt"))
   om.Register(option.NewOption("FILTER_BY_DOMAIN", false, false, "Filter subdomains to match only the base domain"))
 
 	helpManager := help.NewHelpManager()
-	helpManager.Register("subdomains_search", "Subdomains search module", [][]string{
-		{"SOURCES_URI", "not changable", "Uri from where to fetch subdomains about a domain"},
-		{"DOMAIN", "example.com", "The domain to search for subdomains"},
-		{"FILTER_BY_DOMAIN", "false", "Filter subdomains to match only the base domain"},
-	})
+	helpManager.RegisterOptions("subdomains_search", "Subdomains search module", om)
 
 	return &SubdomainsSearch{
 		optionManager: om,
@@ -207,45 +217,55 @@ func (s *SubdomainsSearch) Options() []map[string]string {
 // Run launches the subdomain enumeration across all configured sources
 func (s *SubdomainsSearch) Run(ctx context.Context) [][]string {
     var allSubdomains []string
-    var sources []string
-    var domain string
 
     // Prendi le sorgenti e il dominio dalle opzioni
-    if sopt, _ := s.optionManager.Get("SOURCES_URI"); sopt.Value != nil {
-        sources = sopt.Value.([]string)
-    }
-    if dopt, _ := s.optionManager.Get("DOMAIN"); dopt.Value != nil {
-        domain = dopt.Value.(string)
+    sources := s.optionManager.Strings("SOURCES_URI")
+    var domains []string
+    for _, t := range s.optionManager.Strings("DOMAIN") {
+        if host := target.Host(t); scope.Allowed(ctx, host) {
+            domains = append(domains, host)
+        }
     }
+    filter := s.optionManager.Bool("FILTER_BY_DOMAIN")
 
-    ch := make(chan []string, len(sources))
-    var wg sync.WaitGroup
-
-    // Disparo una goroutine per ogni fonte
-    for _, u := range sources {
-        // Controllo se è già arrivata la cancel
-        select {
-        case <-ctx.Done():
-            return nil
-        default:
-        }
+    s.progress.Start(len(sources) * len(domains))
+    defer s.progress.Finish()
 
-        wg.Add(1)
-        go func(url string) {
-            defer wg.Done()
+    ch := make(chan []string, len(sources)*len(domains))
+    var wg sync.WaitGroup
 
-            // Anche dentro il worker controllo la cancel
+    // Disparo una goroutine per ogni fonte e dominio
+dispatch:
+    for _, domain := range domains {
+        for _, u := range sources {
+            // On cancel stop dispatching, the collect loop keeps what was found so far
             select {
             case <-ctx.Done():
-                return
+                break dispatch
             default:
             }
 
-            subs, err := s.fetchSubdomains(url, domain)
-            if err == nil {
-                ch <- subs
-            }
-        }(u)
+            wg.Add(1)
+            go func(url, domain string) {
+                defer wg.Done()
+
+                // Anche dentro il worker controllo la cancel
+                select {
+                case <-ctx.Done():
+                    return
+                default:
+                }
+
+                subs, err := s.fetchSubdomains(ctx, url, domain)
+                s.progress.Inc()
+                if err == nil {
+                    if filter {
+                        subs = filterByDomain(subs, domain)
+                    }
+                    ch <- subs
+                }
+            }(u, domain)
+        }
     }
 
     // Chiudo il canale quando tutte le fetch sono completate
@@ -254,34 +274,35 @@ func (s *SubdomainsSearch) Run(ctx context.Context) [][]string {
         close(ch)
     }()
 
-    // Raccolgo i risultati finché non arriva una cancel o il canale non viene chiuso
+    // Raccolgo i risultati finché non arriva una cancel o il canale non viene chiuso;
+    // in caso di cancel si tengono quelli trovati finora
+    seen := make(map[string]bool)
     for {
         select {
         case <-ctx.Done():
-            return nil
+            goto EMIT
         case subs, ok := <-ch:
             if !ok {
                 goto EMIT
             }
-            allSubdomains = append(allSubdomains, subs...)
+            for _, sub := range subs {
+                if !seen[sub] {
+                    seen[sub] = true
+                    allSubdomains = append(allSubdomains, sub)
+                    stream.Emit(ctx, []string{sub})
+                }
+            }
         }
     }
 
-
-
 EMIT:
-
-    if fopt, _ := s.optionManager.Get("FILTER_BY_DOMAIN"); fopt.Value != nil {
-		    if fopt.Value.(bool) {
-				    allSubdomains = filterByDomain(allSubdomains, domain)
-		    }
-    }
     // Unisco, filtro i duplicati e ordino
     s.results = s.uniqueSortedList(allSubdomains)
 
     var table [][]string
     for _, d := range s.results {
         table = append(table, []string{d})
+        s.findings.AddSubdomain(d, s.prompt)
     }
     return table
 }
@@ -308,30 +329,40 @@ func (s *SubdomainsSearch) Save(filename string) error {
 
 // Set allows the user to modify a modifiable option (except SOURCES_URI)
 func (s *SubdomainsSearch) Set(n string, v string) []string {
-	if n == "SOURCES_URI" {
-		return []string{"SOURCES_URI", "cannot change this value"}
+	if strings.EqualFold(n, "SOURCES_URI") {
+		return []string{"Error", "SOURCES_URI cannot be changed"}
 	}
+	return s.optionManager.Apply(n, v)
+}
 
-	om := *s.optionManager
-	m, ok := om.Get(n)
-	if ok {
-		if n == "FILTER_BY_DOMAIN" {
-			switch strings.ToLower(v) {
-			case "true":
-				m.Set(true)
-				return []string{m.Name, "true"}
-			case "false":
-				m.Set(false)
-				return []string{m.Name, "false"}
-			default:
-				return []string{m.Name, "Value must be 'true' or 'false'"}
-			}
-		}
+// Export serializes the discovered subdomains
+func (s *SubdomainsSearch) Export() ([]byte, error) {
+	return json.Marshal(s.results)
+}
 
-		m.Set(v)
-		return []string{m.Name, fmt.Sprintf("%v", m.Value)}
+// Import restores subdomains produced by Export
+func (s *SubdomainsSearch) Import(data []byte) error {
+	var results []string
+	if err := json.Unmarshal(data, &results); err != nil {
+		return err
 	}
-	return []string{"Error", "Option not found"}
+	s.results = results
+	return nil
+}
+
+// SetFindings connects the module to the shared findings store
+func (s *SubdomainsSearch) SetFindings(store *findings.Store) {
+	s.findings = store
+}
+
+// Progress returns the number of sources queried so far
+func (s *SubdomainsSearch) Progress() progress.Status {
+	return s.progress.Status()
+}
+
+// OptionManager returns the options of the module
+func (s *SubdomainsSearch) OptionManager() *option.OptionManager {
+	return s.optionManager
 }
 
 // Help returns usage help for the CLI
@@ -348,6 +379,11 @@ func (s *SubdomainsSearch) Results() [][]string {
     return res
 }
 
+// Headers returns the names of the result columns
+func (s *SubdomainsSearch) Headers() []string {
+	return []string{"SUBDOMAIN"}
+}
+
 // Metadata functions
 func (s *SubdomainsSearch) Name() string     { return s.name }
 func (s *SubdomainsSearch) Author() string   { return s.author }
@@ -4,13 +4,16 @@ import (
     "encoding/json"
     "fmt"
     "os"
-    "strconv"
     "strings"
     "net/url"
     "net"
     "context"
 
+    "github.com/czz/oblivion/utils/findings"
     "github.com/czz/oblivion/utils/option"
+    "github.com/czz/oblivion/utils/progress"
+    "github.com/czz/oblivion/utils/scope"
+    "github.com/czz/oblivion/utils/stream"
     "github.com/czz/oblivion/utils/help"
     "github.com/go-rod/rod/lib/proto"
     "github.com/go-rod/rod"
@@ -35,29 +38,25 @@ type WebSpider struct {
     running      bool
     visited      map[string]bool
     table        [][]string
+    findings     *findings.Store
+    progress     progress.Counter
 }
 
 func NewWebSpider() *WebSpider {
     om := option.NewOptionManager()
 
-    om.Register(option.NewOption("TARGETS", []string{}, true, "Target URLs to crawl"))
+    om.Register(option.NewOption("TARGETS", []string{}, true, "Target URLs to crawl").WithType(option.TypeTargets).WithValidator(validateURLs).WithExample("https://example.com or https://a.com,https://b.com or /pathtofile.txt"))
     om.Register(option.NewOption("DEPTH", 1, false, "Crawl depth"))
-    om.Register(option.NewOption("SAVE_HTML", false, false, "Save full HTML content"))
+    om.Register(option.NewOption("SAVE_HTML", false, false, "Save full HTML content in the results saved with save"))
     om.Register(option.NewOption("USER_AGENT", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36", false, "User-Agent string to use"))
-    om.Register(option.NewOption("ALLOWED_DOMAINS", []string{}, false, "List of allowed domains for recursion"))
+    om.Register(option.NewOption("ALLOWED_DOMAINS", []string{}, false, "List of allowed domains for recursion").WithValidator(validateDomains).
+        WithExample("example.com,*.example.com or https://example.com/app").
+        WithDoc("Links are followed only when they match one of the entries, with the rules of scope: example.com is the host only, *.example.com its subdomains. Empty follows every link."))
     om.Register(option.NewOption("INCLUDE_CATEGORIES", true, false, "Categorize extracted links (scripts, images, media, etc.)"))
-    om.Register(option.NewOption("HTTP_PROXY", "", false, "HTTP proxy to use (e.g. http://127.0.0.1:8080)"))
+    om.Register(option.NewOption("HTTP_PROXY", "", false, "HTTP proxy to use").WithType(option.TypeURL))
 
     helpManager := help.NewHelpManager()
-    helpManager.Register("webspider", "Webspider module", [][]string{
-        {"TARGETS", "example.com or abc.com,def.com or /pathtofile.txt", "Target URLs to crawl"},
-        {"DEPTH", "1", "Crawl depth"},
-        {"SAVE_HTML", "true or false", "Timeout in seconds"},
-        {"USER_AGENT", "Mozilla/5.0 ...", "User agent string"},
-        {"ALLOWED_DOMAINS", "example.com or abc.com,def.com or /pathtofile.txt", "List of allowed domains"},
-        {"INCLUDE_CATEGORIES", "true or false", "Classify links into images, scripts, media, etc."},
-        {"HTTP_PROXY", "", "HTTP proxy to use (e.g. http://127.0.0.1:8080)"},
-    })
+    helpManager.RegisterOptions("webspider", "Webspider module", om)
 
     return &WebSpider{
         optionManager: om,
@@ -71,36 +70,20 @@ func NewWebSpider() *WebSpider {
 }
 
 func (w *WebSpider) Run(ctx context.Context) [][]string {
-    var targets []string
-    var saveFullHTML bool
-    var depth int
-    var userAgent string
-    var allowedDomains []string
-    var includeCategories = true
-    var proxy string
-
-
-    if v, ok := w.optionManager.Get("TARGETS"); ok {
-        targets = v.Value.([]string)
-    }
-    if v, ok := w.optionManager.Get("DEPTH"); ok {
-        depth = v.Value.(int)
-    }
-    if v, ok := w.optionManager.Get("SAVE_HTML"); ok {
-        saveFullHTML = v.Value.(bool)
-    }
-    if v, ok := w.optionManager.Get("USER_AGENT"); ok {
-        userAgent = v.Value.(string)
-    }
-    if v, ok := w.optionManager.Get("ALLOWED_DOMAINS"); ok {
-        allowedDomains = v.Value.([]string)
-    }
-    if v, ok := w.optionManager.Get("INCLUDE_CATEGORIES"); ok {
-        includeCategories = v.Value.(bool)
-    }
-    if v, ok := w.optionManager.Get("HTTP_PROXY"); ok {
-        proxy = v.Value.(string)
+    targets := w.optionManager.Strings("TARGETS")
+    depth := w.optionManager.Int("DEPTH")
+    saveFullHTML := w.optionManager.Bool("SAVE_HTML")
+    userAgent := w.optionManager.String("USER_AGENT")
+    allowedDomains := scope.New()
+    for _, d := range w.optionManager.Strings("ALLOWED_DOMAINS") {
+        allowedDomains.Add(d, false) // Checked by validateDomains
     }
+    includeCategories := w.optionManager.Bool("INCLUDE_CATEGORIES")
+    proxy := w.optionManager.String("HTTP_PROXY")
+
+    // Pages are discovered while crawling, so only the crawled ones are counted
+    w.progress.Start(0)
+    defer w.progress.Finish()
 
     var rows [][]string
     for _, u := range targets {
@@ -119,7 +102,7 @@ func (w *WebSpider) Run(ctx context.Context) [][]string {
     return rows
 }
 
-func (w *WebSpider) recursiveCrawl(ctx context.Context,urlStr string, includeHTML bool, currentDepth, maxDepth int, userAgent string, allowedDomains []string, includeCategories bool, proxy string, rows *[][]string) {
+func (w *WebSpider) recursiveCrawl(ctx context.Context,urlStr string, includeHTML bool, currentDepth, maxDepth int, userAgent string, allowedDomains *scope.Scope, includeCategories bool, proxy string, rows *[][]string) {
     // Respect depth and visited
     if currentDepth >= maxDepth || w.visited[urlStr] {
         return
@@ -132,25 +115,37 @@ func (w *WebSpider) recursiveCrawl(ctx context.Context,urlStr string, includeHTM
     }
 
     w.visited[urlStr] = true
-    result := w.crawl(urlStr, includeHTML, userAgent, proxy, includeCategories)
+    if !scope.Allowed(ctx, urlStr) {
+        return
+    }
+    result := w.crawl(ctx, urlStr, includeHTML, userAgent, proxy, includeCategories)
+    w.progress.Inc()
     w.results = append(w.results, result)
+    w.findings.AddURL(result.URL, 0, result.Title, w.prompt)
+    for _, link := range result.Links {
+        w.findings.AddURL(link, 0, "", w.prompt)
+    }
 
-    *rows = append(*rows, []string{"URL", "TITLE", "TOTAL LINKS"})
-    *rows = append(*rows, []string{"---", "-----", "-----------"})
-    *rows = append(*rows, []string{result.URL, result.Title, fmt.Sprintf("%d links", len(result.Links))})
-    *rows = append(*rows, []string{"", "", ""})
+    // One row for the page and one per link found on it (see Headers)
+    page := [][]string{
+        {result.URL, result.Title, fmt.Sprintf("%d", len(result.Links)), ""},
+    }
     for _, link := range result.Links {
-        *rows = append(*rows, []string{link, "", ""})
+        page = append(page, []string{link, "", "", result.URL})
+    }
+    for _, row := range page {
+        stream.Emit(ctx, row)
     }
+    *rows = append(*rows, page...)
 
     for _, link := range result.Links {
-        if isAllowed(link, allowedDomains) {
+        if allowedDomains.Contains(link) {
             w.recursiveCrawl(ctx, link, includeHTML, currentDepth+1, maxDepth, userAgent, allowedDomains, includeCategories, proxy, rows)
         }
     }
 }
 
-func (w *WebSpider) crawl(urlStr string, includeHTML bool, userAgent string, proxy string, includeCategories bool) CrawlResult {
+func (w *WebSpider) crawl(ctx context.Context, urlStr string, includeHTML bool, userAgent string, proxy string, includeCategories bool) CrawlResult {
     if !isResolvable(urlStr) {
         return CrawlResult{URL: urlStr, Title: "Unresolvable host", Links: []string{}}
     }
@@ -169,6 +164,19 @@ func (w *WebSpider) crawl(urlStr string, includeHTML bool, userAgent string, pro
         return CrawlResult{URL: urlStr, Title: "Failed to create page: " + err.Error(), Links: []string{}}
     }
 
+    // Every request of the page, redirects and resources included, goes through the scope
+    router := page.HijackRequests()
+    router.MustAdd("*", func(h *rod.Hijack) {
+        u := h.Request.URL()
+        if (u.Scheme == "http" || u.Scheme == "https") && !scope.Allowed(ctx, u.String()) {
+            h.Response.Fail(proto.NetworkErrorReasonBlockedByClient)
+            return
+        }
+        h.ContinueRequest(&proto.FetchContinueRequest{})
+    })
+    go router.Run()
+    defer router.MustStop()
+
     if err := page.Navigate(urlStr); err != nil {
         return CrawlResult{URL: urlStr, Title: "Navigation failed: " + err.Error(), Links: []string{}}
     }
@@ -216,7 +224,8 @@ func (w *WebSpider) crawl(urlStr string, includeHTML bool, userAgent string, pro
             if resolved.Scheme != "http" && resolved.Scheme != "https" {
                 continue
             }
-            if isResolvable(resolved.String()) {
+            // Out of scope links are listed but never contacted, not even by DNS
+            if !scope.In(ctx, resolved.String()) || isResolvable(resolved.String()) {
                 links = append(links, resolved.String())
             }
         }
@@ -248,6 +257,28 @@ func (w *WebSpider) Save(filename string) error {
     return encoder.Encode(w.results)
 }
 
+// spiderState is the serialized form of the crawl results used by Export/Import.
+type spiderState struct {
+    Table   [][]string    `json:"table"`
+    Results []CrawlResult `json:"results"`
+}
+
+// Export serializes the crawl results.
+func (w *WebSpider) Export() ([]byte, error) {
+    return json.Marshal(spiderState{Table: w.table, Results: w.results})
+}
+
+// Import restores crawl results produced by Export.
+func (w *WebSpider) Import(data []byte) error {
+    var st spiderState
+    if err := json.Unmarshal(data, &st); err != nil {
+        return err
+    }
+    w.table = st.Table
+    w.results = st.Results
+    return nil
+}
+
 func (w *WebSpider) Options() []map[string]string {
     opt := make([]map[string]string, len(w.optionManager.List()))
     for i, v := range w.optionManager.List() {
@@ -256,73 +287,44 @@ func (w *WebSpider) Options() []map[string]string {
     return opt
 }
 
+// Set parses and sets an option.
 func (w *WebSpider) Set(n string, v string) []string {
-    opt, ok := w.optionManager.Get(n)
-    if ok {
-        switch opt.Name {
-        case "TARGETS":
-            var targets []string
-            v = strings.TrimSpace(v)
-            if fileInfo, err := os.Stat(v); err == nil && !fileInfo.IsDir() {
-                content, err := os.ReadFile(v)
-                if err != nil {
-                    return []string{"TARGETS", "Error reading file"}
-                }
-                lines := strings.Split(string(content), "\n")
-                for _, line := range lines {
-                    line = strings.TrimSpace(line)
-                    if line != "" {
-                        if !checkURL(line) {
-                            return []string{"TARGETS", "Error: url must start with http:// or https://"}
-                        }
-                        targets = append(targets, line)
-                    }
-                }
-            } else {
-                for _, t := range strings.Split(v, ",") {
-                    t = strings.TrimSpace(t)
-                    if t != "" {
-                        if !checkURL(t) {
-                            return []string{"TARGETS", "Error: url must start with http:// or https://"}
-                        }
-                        targets = append(targets, t)
-                    }
-                }
-            }
-            opt.Set(targets)
-            return []string{n, fmt.Sprint(targets)}
-        case "DEPTH":
-            if depth, err := strconv.Atoi(v); err == nil {
-                opt.Set(depth)
-                return []string{n, v}
-            }
-            return []string{n, "Invalid depth"}
-        case "SAVE_HTML":
-            opt.Set((v == "true"))
-            return []string{n, v}
-        case "USER_AGENT":
-            opt.Set(v)
-            return []string{n, v}
-        case "ALLOWED_DOMAINS":
-            var domains []string
-            for _, d := range strings.Split(v, ",") {
-                d = strings.TrimSpace(d)
-                if d != "" {
-                    domains = append(domains, d)
-                }
-            }
-            opt.Set(domains)
-            return []string{n, fmt.Sprint(domains)}
-        case "INCLUDE_CATEGORIES":
-            opt.Set((v == "true"))
-            return []string{n, v}
-        default:
-        		opt.Set(v)
-        		return []string{opt.Name, fmt.Sprint(v)}
+    return w.optionManager.Apply(n, v)
+}
+
+// validateURLs checks that every target is an http(s) URL.
+func validateURLs(v interface{}) error {
+    for _, t := range v.([]string) {
+        if !checkURL(t) {
+            return fmt.Errorf("%s: url must start with http:// or https://", t)
+        }
+    }
+    return nil
+}
 
+func validateDomains(v interface{}) error {
+    s := scope.New()
+    for _, d := range v.([]string) {
+        if err := s.Add(d, false); err != nil {
+            return err
         }
     }
-    return []string{"Error", "Option not found"}
+    return nil
+}
+
+// SetFindings connects the spider to the shared findings store.
+func (w *WebSpider) SetFindings(store *findings.Store) {
+    w.findings = store
+}
+
+// Progress returns the number of pages crawled so far.
+func (w *WebSpider) Progress() progress.Status {
+    return w.progress.Status()
+}
+
+// OptionManager returns the options of the spider.
+func (w *WebSpider) OptionManager() *option.OptionManager {
+    return w.optionManager
 }
 
 func (w *WebSpider) Help() [][]string {
@@ -334,6 +336,26 @@ func (w *WebSpider) Results() [][]string {
     return w.table
 }
 
+// Records returns the crawled pages with their links, exported by the json formats.
+func (w *WebSpider) Records() []interface{} {
+    out := make([]interface{}, len(w.results))
+    for i, r := range w.results {
+        out[i] = r
+    }
+    return out
+}
+
+// Headers returns the names of the result columns: crawled pages have a title and
+// a number of links, links have the page they were found on.
+func (w *WebSpider) Headers() []string {
+    return []string{"URL", "TITLE", "LINKS", "FOUND ON"}
+}
+
+// DiffColumns matches the results of two runs by URL and the page linking it.
+func (w *WebSpider) DiffColumns() (keys, ignore []string) {
+    return []string{"URL", "FOUND ON"}, nil
+}
+
 func (w *WebSpider) Name() string       { return w.name }
 func (w *WebSpider) Author() string     { return w.author }
 func (w *WebSpider) Description() string { return w.desc }
@@ -342,18 +364,6 @@ func (w *WebSpider) Running() bool      { return w.running }
 func (w *WebSpider) Start() error       { w.running = true; return nil }
 func (w *WebSpider) Stop() error        { w.running = false; return nil }
 
-func isAllowed(link string, domains []string) bool {
-    if len(domains) == 0 {
-        return true
-    }
-    for _, domain := range domains {
-        if strings.Contains(link, domain) {
-            return true
-        }
-    }
-    return false
-}
-
 func checkURL(url string) bool {
     return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
 }
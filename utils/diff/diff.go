@@ -0,0 +1,240 @@
+package diff
+
+/*
+Diff compares two runs of a module, matching rows by their key columns:
+
+    d, err := diff.Compare([]string{"HOST", "PORT", "BANNER"}, []string{"HOST", "PORT"}, nil, before, after)
+    // a port only in after ➜ added, only in before ➜ removed,
+    // in both with another banner ➜ changed
+
+Without key columns the whole row is the key, so rows are only added or removed.
+Ignored columns, such as response times, do not make a row changed.
+*/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "github.com/czz/oblivion/utils/results"
+)
+
+// Change kinds.
+const (
+    Added   = "added"
+    Removed = "removed"
+    Changed = "changed"
+)
+
+// Change is a row that differs between two runs.
+type Change struct {
+    Kind string   // Added, Removed or Changed
+    Old  []string // Row of the first run, nil when added
+    New  []string // Row of the second run, nil when removed
+}
+
+// Diff is the result of comparing two runs.
+type Diff struct {
+    Module    string   // Module the runs belong to
+    From      string   // ID of the first run
+    To        string   // ID of the second run
+    Headers   []string // Column names
+    Keys      []int    // Key columns, empty when the whole row is the key
+    Ignored   []int    // Columns not compared
+    Changes   []Change // Added, then changed, then removed rows
+    Added     int
+    Removed   int
+    Changed   int
+    Unchanged int
+}
+
+// Compare returns the rows added, removed and changed from before to after. keys
+// names the columns identifying a row and ignore the columns left out of the
+// comparison, by name or 0-based index.
+func Compare(headers, keys, ignore []string, before, after [][]string) (*Diff, error) {
+    d := &Diff{Headers: results.Complete(results.Complete(headers, before), after)}
+    var err error
+    if d.Keys, err = columns(d.Headers, keys); err != nil {
+        return nil, err
+    }
+    if d.Ignored, err = columns(d.Headers, ignore); err != nil {
+        return nil, err
+    }
+
+    old := make(map[string][]string, len(before))
+    var oldOrder []string
+    for _, row := range before {
+        k := d.key(row)
+        if _, dup := old[k]; !dup {
+            old[k] = row
+            oldOrder = append(oldOrder, k)
+        }
+    }
+
+    seen := make(map[string]bool, len(after))
+    var added, changed []Change
+    for _, row := range after {
+        k := d.key(row)
+        if seen[k] {
+            continue
+        }
+        seen[k] = true
+        prev, ok := old[k]
+        switch {
+        case !ok:
+            added = append(added, Change{Kind: Added, New: row})
+        case !d.equal(prev, row):
+            changed = append(changed, Change{Kind: Changed, Old: prev, New: row})
+        default:
+            d.Unchanged++
+        }
+    }
+    var removed []Change
+    for _, k := range oldOrder {
+        if !seen[k] {
+            removed = append(removed, Change{Kind: Removed, Old: old[k]})
+        }
+    }
+
+    d.Added, d.Changed, d.Removed = len(added), len(changed), len(removed)
+    d.Changes = append(append(added, changed...), removed...)
+    return d, nil
+}
+
+// columns returns the indexes of the named columns.
+func columns(headers, names []string) ([]int, error) {
+    var out []int
+    for _, name := range names {
+        i, err := results.Column(headers, name)
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, i)
+    }
+    return out, nil
+}
+
+// key returns the identity of a row.
+func (d *Diff) key(row []string) string {
+    if len(d.Keys) == 0 {
+        parts := make([]string, 0, len(d.Headers))
+        for i := range d.Headers {
+            if !d.ignored(i) {
+                parts = append(parts, cell(row, i))
+            }
+        }
+        return strings.Join(parts, "\x00")
+    }
+    parts := make([]string, len(d.Keys))
+    for i, c := range d.Keys {
+        parts[i] = cell(row, c)
+    }
+    return strings.Join(parts, "\x00")
+}
+
+// Summary describes the number of changes, e.g. "2 added, 1 removed, 0 changed, 10 unchanged".
+func (d *Diff) Summary() string {
+    return fmt.Sprintf("%d added, %d removed, %d changed, %d unchanged", d.Added, d.Removed, d.Changed, d.Unchanged)
+}
+
+// Table returns the changes as rows prefixed by their kind (+, - or ~), with a
+// header row. Changed cells read "old -> new".
+func (d *Diff) Table() [][]string {
+    table := [][]string{append([]string{""}, d.Headers...)}
+    for _, c := range d.Changes {
+        row := []string{"+"}
+        switch c.Kind {
+        case Removed:
+            row = append([]string{"-"}, pad(c.Old, len(d.Headers))...)
+        case Changed:
+            row = []string{"~"}
+            for i := range d.Headers {
+                o, n := cell(c.Old, i), cell(c.New, i)
+                if o != n && !d.ignored(i) {
+                    row = append(row, o+" -> "+n)
+                } else {
+                    row = append(row, n)
+                }
+            }
+        default:
+            row = append(row, pad(c.New, len(d.Headers))...)
+        }
+        table = append(table, row)
+    }
+    return table
+}
+
+// MarshalJSON writes the diff with the rows as objects keyed by header:
+// {"module", "from", "to", "summary": {...}, "changes": [{"change", "old", "new"}]}.
+func (d *Diff) MarshalJSON() ([]byte, error) {
+    type change struct {
+        Change string            `json:"change"`
+        Old    map[string]string `json:"old,omitempty"`
+        New    map[string]string `json:"new,omitempty"`
+    }
+    type summary struct {
+        Added     int `json:"added"`
+        Removed   int `json:"removed"`
+        Changed   int `json:"changed"`
+        Unchanged int `json:"unchanged"`
+    }
+
+    changes := make([]change, 0, len(d.Changes))
+    for _, c := range d.Changes {
+        changes = append(changes, change{Change: c.Kind, Old: d.object(c.Old), New: d.object(c.New)})
+    }
+    return json.Marshal(struct {
+        Module  string   `json:"module"`
+        From    string   `json:"from"`
+        To      string   `json:"to"`
+        Summary summary  `json:"summary"`
+        Changes []change `json:"changes"`
+    }{d.Module, d.From, d.To, summary{d.Added, d.Removed, d.Changed, d.Unchanged}, changes})
+}
+
+// object returns a row as header -> value, nil for a missing row.
+func (d *Diff) object(row []string) map[string]string {
+    if row == nil {
+        return nil
+    }
+    obj := make(map[string]string, len(d.Headers))
+    for i, h := range d.Headers {
+        obj[h] = cell(row, i)
+    }
+    return obj
+}
+
+// equal reports whether two rows have the same compared cells.
+func (d *Diff) equal(a, b []string) bool {
+    for i := range d.Headers {
+        if !d.ignored(i) && cell(a, i) != cell(b, i) {
+            return false
+        }
+    }
+    return true
+}
+
+// ignored reports whether column i is left out of the comparison.
+func (d *Diff) ignored(i int) bool {
+    for _, c := range d.Ignored {
+        if c == i {
+            return true
+        }
+    }
+    return false
+}
+
+// pad returns row with exactly n cells.
+func pad(row []string, n int) []string {
+    out := make([]string, n)
+    copy(out, row)
+    return out
+}
+
+// cell returns column i of a row, or "" for short rows.
+func cell(row []string, i int) string {
+    if i < len(row) {
+        return row[i]
+    }
+    return ""
+}
@@ -0,0 +1,268 @@
+package export
+
+/*
+Export writes module results in a common set of formats:
+
+    d := export.Data{Name: "portscanner", Headers: []string{"HOST", "PORT"}, Rows: rows}
+    err := export.Save("/tmp/scan.csv", "csv", d)
+
+json and jsonl write Records when the module provides structured results, rows as
+objects keyed by header otherwise. sarif (SARIF 2.1.0) and findings (one finding
+per line) write the Findings of the modules reporting vulnerabilities, and are empty
+for the others. The other formats always write the table.
+*/
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "encoding/xml"
+    "fmt"
+    "html"
+    "io"
+    "os"
+    "regexp"
+    "strings"
+
+    "github.com/czz/oblivion/utils/findings"
+    "github.com/czz/oblivion/utils/results"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{"json", "jsonl", "csv", "tsv", "txt", "md", "html", "xml", "sarif", "findings"}
+
+// Data is what gets exported.
+type Data struct {
+    Name     string                   // Module name, used as title by html and xml
+    Headers  []string                 // Column names; missing ones are named after the column index
+    Rows     [][]string               // Result table
+    Records  []interface{}            // Structured results written by json and jsonl, optional
+    Findings []findings.Vulnerability // Findings written by sarif and findings, optional
+}
+
+// Valid reports whether format is supported.
+func Valid(format string) bool {
+    for _, f := range Formats {
+        if f == format {
+            return true
+        }
+    }
+    return false
+}
+
+// Save writes d to a file in the given format.
+func Save(path, format string, d Data) error {
+    if !Valid(format) {
+        return fmt.Errorf("unknown format %s (available: %s)", format, strings.Join(Formats, ", "))
+    }
+    f, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    w := bufio.NewWriter(f)
+    if err := Write(w, format, d); err != nil {
+        f.Close()
+        return err
+    }
+    if err := w.Flush(); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// Write writes d to w in the given format.
+func Write(w io.Writer, format string, d Data) error {
+    d.Headers = results.Complete(d.Headers, d.Rows)
+    switch format {
+    case "json":
+        encoder := json.NewEncoder(w)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(records(d))
+    case "jsonl":
+        encoder := json.NewEncoder(w)
+        for _, r := range records(d) {
+            if err := encoder.Encode(r); err != nil {
+                return err
+            }
+        }
+        return nil
+    case "csv", "tsv":
+        return writeCSV(w, format == "tsv", d)
+    case "txt":
+        for _, row := range d.Rows {
+            if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+                return err
+            }
+        }
+        return nil
+    case "md":
+        return writeMarkdown(w, d)
+    case "html":
+        return writeHTML(w, d)
+    case "xml":
+        return writeXML(w, d)
+    case "sarif":
+        return findings.WriteSARIF(w, d.Findings)
+    case "findings":
+        return findings.WriteJSONL(w, d.Findings)
+    }
+    return fmt.Errorf("unknown format %s (available: %s)", format, strings.Join(Formats, ", "))
+}
+
+// records returns the structured results of d, or its rows as header -> value objects.
+func records(d Data) []interface{} {
+    if d.Records != nil {
+        return d.Records
+    }
+    out := make([]interface{}, 0, len(d.Rows))
+    for _, row := range d.Rows {
+        obj := make(map[string]string, len(d.Headers))
+        for i, h := range d.Headers {
+            obj[h] = cell(row, i)
+        }
+        out = append(out, obj)
+    }
+    return out
+}
+
+// writeCSV writes the header and the rows, quoting cells as needed.
+func writeCSV(w io.Writer, tabs bool, d Data) error {
+    cw := csv.NewWriter(w)
+    if tabs {
+        cw.Comma = '\t'
+    }
+    if err := cw.Write(d.Headers); err != nil {
+        return err
+    }
+    for _, row := range d.Rows {
+        if err := cw.Write(pad(row, len(d.Headers))); err != nil {
+            return err
+        }
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// mdEscaper escapes the characters breaking a markdown table cell.
+var mdEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// writeMarkdown writes a markdown table.
+func writeMarkdown(w io.Writer, d Data) error {
+    line := func(cells []string) error {
+        escaped := make([]string, len(cells))
+        for i, c := range cells {
+            escaped[i] = mdEscaper.Replace(c)
+        }
+        _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+        return err
+    }
+
+    if err := line(d.Headers); err != nil {
+        return err
+    }
+    sep := make([]string, len(d.Headers))
+    for i := range sep {
+        sep[i] = "---"
+    }
+    if err := line(sep); err != nil {
+        return err
+    }
+    for _, row := range d.Rows {
+        if err := line(pad(row, len(d.Headers))); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// writeHTML writes a standalone HTML page with the results table.
+func writeHTML(w io.Writer, d Data) error {
+    var b strings.Builder
+    title := html.EscapeString(d.Name + " results")
+    b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+    b.WriteString("<title>" + title + "</title>\n")
+    b.WriteString("<style>table{border-collapse:collapse;font-family:monospace}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left}th{background:#eee}</style>\n")
+    b.WriteString("</head>\n<body>\n<h1>" + title + "</h1>\n<table>\n<tr>")
+    for _, h := range d.Headers {
+        b.WriteString("<th>" + html.EscapeString(h) + "</th>")
+    }
+    b.WriteString("</tr>\n")
+    for _, row := range d.Rows {
+        b.WriteString("<tr>")
+        for _, c := range pad(row, len(d.Headers)) {
+            b.WriteString("<td>" + html.EscapeString(c) + "</td>")
+        }
+        b.WriteString("</tr>\n")
+    }
+    b.WriteString("</table>\n</body>\n</html>\n")
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// invalidXMLName matches the characters not allowed in element names.
+var invalidXMLName = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// xmlName turns a header into an element name.
+func xmlName(h string) string {
+    name := strings.Trim(invalidXMLName.ReplaceAllString(strings.ToLower(h), "_"), "_")
+    if name == "" || !(name[0] >= 'a' && name[0] <= 'z' || name[0] == '_') {
+        name = "column_" + name
+    }
+    return name
+}
+
+// writeXML writes <results module="..."><result><header>value</header>...</result></results>.
+func writeXML(w io.Writer, d Data) error {
+    if _, err := io.WriteString(w, xml.Header); err != nil {
+        return err
+    }
+    enc := xml.NewEncoder(w)
+    enc.Indent("", "  ")
+
+    root := xml.StartElement{Name: xml.Name{Local: "results"}, Attr: []xml.Attr{{Name: xml.Name{Local: "module"}, Value: d.Name}}}
+    if err := enc.EncodeToken(root); err != nil {
+        return err
+    }
+    names := make([]string, len(d.Headers))
+    for i, h := range d.Headers {
+        names[i] = xmlName(h)
+    }
+    for _, row := range d.Rows {
+        result := xml.StartElement{Name: xml.Name{Local: "result"}}
+        if err := enc.EncodeToken(result); err != nil {
+            return err
+        }
+        for i, name := range names {
+            if err := enc.EncodeElement(cell(row, i), xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+                return err
+            }
+        }
+        if err := enc.EncodeToken(result.End()); err != nil {
+            return err
+        }
+    }
+    if err := enc.EncodeToken(root.End()); err != nil {
+        return err
+    }
+    if err := enc.Flush(); err != nil {
+        return err
+    }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
+
+// pad returns row with exactly n cells.
+func pad(row []string, n int) []string {
+    out := make([]string, n)
+    copy(out, row)
+    return out
+}
+
+// cell returns column i of a row, or "" for short rows.
+func cell(row []string, i int) string {
+    if i < len(row) {
+        return row[i]
+    }
+    return ""
+}
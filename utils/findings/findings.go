@@ -0,0 +1,414 @@
+package findings
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Host is an IP address or hostname discovered during the session.
+type Host struct {
+    Address   string    `json:"address"`    // IP address or hostname
+    Module    string    `json:"module"`     // Module that first found the host
+    FirstSeen time.Time `json:"first_seen"` // When the host was first recorded
+}
+
+// Subdomain is a subdomain discovered by enumeration or brute force.
+type Subdomain struct {
+    Name      string    `json:"name"`
+    Module    string    `json:"module"`
+    FirstSeen time.Time `json:"first_seen"`
+}
+
+// Service is an open port on a host, with the banner grabbed from it.
+type Service struct {
+    Host      string    `json:"host"`
+    Port      int       `json:"port"`
+    Protocol  string    `json:"protocol"`
+    Banner    string    `json:"banner,omitempty"`
+    Module    string    `json:"module"`
+    FirstSeen time.Time `json:"first_seen"`
+}
+
+// URL is a web resource found by crawling or fuzzing.
+type URL struct {
+    URL       string    `json:"url"`
+    Status    int       `json:"status,omitempty"` // HTTP status code, 0 when unknown
+    Title     string    `json:"title,omitempty"`
+    Module    string    `json:"module"`
+    FirstSeen time.Time `json:"first_seen"`
+}
+
+// Vulnerability is a security issue reported by a module.
+type Vulnerability struct {
+    ID        string    `json:"id"`       // Stable identifier (module, title and target)
+    Title     string    `json:"title"`
+    Severity  string    `json:"severity"` // info, low, medium, high or critical
+    Target    string    `json:"target"`
+    Evidence  string    `json:"evidence,omitempty"`
+    Module    string    `json:"module"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// Reporter is implemented by modules that record what they discover in a Store.
+type Reporter interface {
+    SetFindings(*Store)
+}
+
+// Store collects typed findings from all modules. It is safe for concurrent use
+// and every method is a no-op on a nil *Store, so modules can report unconditionally.
+type Store struct {
+    mu         sync.Mutex
+    hosts      map[string]*Host
+    subdomains map[string]*Subdomain
+    services   map[string]*Service
+    urls       map[string]*URL
+    vulns      map[string]*Vulnerability
+}
+
+// NewStore creates an empty findings store.
+func NewStore() *Store {
+    s := &Store{}
+    s.Reset()
+    return s
+}
+
+// Reset removes every finding.
+func (s *Store) Reset() {
+    if s == nil {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.hosts = make(map[string]*Host)
+    s.subdomains = make(map[string]*Subdomain)
+    s.services = make(map[string]*Service)
+    s.urls = make(map[string]*URL)
+    s.vulns = make(map[string]*Vulnerability)
+}
+
+// AddHost records a host; already known hosts are left untouched.
+func (s *Store) AddHost(address, module string) {
+    address = normalize(address)
+    if s == nil || address == "" {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.hosts[address]; !ok {
+        s.hosts[address] = &Host{Address: address, Module: module, FirstSeen: time.Now()}
+    }
+}
+
+// AddSubdomain records a subdomain and the corresponding host.
+func (s *Store) AddSubdomain(name, module string) {
+    name = normalize(name)
+    if s == nil || name == "" {
+        return
+    }
+    s.mu.Lock()
+    if _, ok := s.subdomains[name]; !ok {
+        s.subdomains[name] = &Subdomain{Name: name, Module: module, FirstSeen: time.Now()}
+    }
+    s.mu.Unlock()
+    s.AddHost(name, module)
+}
+
+// AddService records an open port; the banner of a known service is updated when not empty.
+func (s *Store) AddService(host string, port int, protocol, banner, module string) {
+    host = normalize(host)
+    if s == nil || host == "" {
+        return
+    }
+    s.AddHost(host, module)
+
+    key := fmt.Sprintf("%s|%d|%s", host, port, protocol)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if svc, ok := s.services[key]; ok {
+        if banner != "" {
+            svc.Banner = banner
+        }
+        return
+    }
+    s.services[key] = &Service{Host: host, Port: port, Protocol: protocol, Banner: banner, Module: module, FirstSeen: time.Now()}
+}
+
+// AddURL records a URL; status and title of a known URL are updated when provided.
+func (s *Store) AddURL(rawURL string, status int, title, module string) {
+    rawURL = strings.TrimSpace(rawURL)
+    if s == nil || rawURL == "" {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if u, ok := s.urls[rawURL]; ok {
+        if status != 0 {
+            u.Status = status
+        }
+        if title != "" {
+            u.Title = title
+        }
+        return
+    }
+    s.urls[rawURL] = &URL{URL: rawURL, Status: status, Title: title, Module: module, FirstSeen: time.Now()}
+}
+
+// AddVulnerability records a vulnerability. The ID is derived from module, title and
+// target when empty, so reporting the same issue twice keeps a single entry.
+func (s *Store) AddVulnerability(v Vulnerability) {
+    if s == nil {
+        return
+    }
+    if v.ID == "" {
+        v.ID = VulnerabilityID(v.Module, v.Title, v.Target)
+    }
+    if v.Timestamp.IsZero() {
+        v.Timestamp = time.Now()
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.vulns[v.ID]; !ok {
+        s.vulns[v.ID] = &v
+    }
+}
+
+// VulnerabilityID builds the stable identifier of a vulnerability.
+func VulnerabilityID(module, title, target string) string {
+    id := strings.ToLower(module + "/" + title + "/" + target)
+    return strings.Map(func(r rune) rune {
+        if r == ' ' {
+            return '-'
+        }
+        return r
+    }, id)
+}
+
+// Hosts returns all hosts sorted by address.
+func (s *Store) Hosts() []Host {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Host, 0, len(s.hosts))
+    for _, h := range s.hosts {
+        out = append(out, *h)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+    return out
+}
+
+// Subdomains returns all subdomains sorted by name.
+func (s *Store) Subdomains() []Subdomain {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Subdomain, 0, len(s.subdomains))
+    for _, d := range s.subdomains {
+        out = append(out, *d)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out
+}
+
+// Services returns all services sorted by host and port.
+func (s *Store) Services() []Service {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Service, 0, len(s.services))
+    for _, svc := range s.services {
+        out = append(out, *svc)
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if out[i].Host != out[j].Host {
+            return out[i].Host < out[j].Host
+        }
+        if out[i].Port != out[j].Port {
+            return out[i].Port < out[j].Port
+        }
+        return out[i].Protocol < out[j].Protocol
+    })
+    return out
+}
+
+// URLs returns all URLs sorted alphabetically.
+func (s *Store) URLs() []URL {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]URL, 0, len(s.urls))
+    for _, u := range s.urls {
+        out = append(out, *u)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].URL < out[j].URL })
+    return out
+}
+
+// Vulnerabilities returns all vulnerabilities, most severe first.
+func (s *Store) Vulnerabilities() []Vulnerability {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Vulnerability, 0, len(s.vulns))
+    for _, v := range s.vulns {
+        out = append(out, *v)
+    }
+    sort.Slice(out, func(i, j int) bool {
+        ri, rj := SeverityRank(out[i].Severity), SeverityRank(out[j].Severity)
+        if ri != rj {
+            return ri > rj
+        }
+        return out[i].ID < out[j].ID
+    })
+    return out
+}
+
+// Vulnerability returns the vulnerability with the given ID.
+func (s *Store) Vulnerability(id string) (Vulnerability, bool) {
+    if s == nil {
+        return Vulnerability{}, false
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if v, ok := s.vulns[id]; ok {
+        return *v, true
+    }
+    return Vulnerability{}, false
+}
+
+// SeverityRank orders severities from info (0) to critical (4); unknown values rank as info.
+func SeverityRank(severity string) int {
+    switch strings.ToLower(severity) {
+    case "low":
+        return 1
+    case "medium":
+        return 2
+    case "high":
+        return 3
+    case "critical":
+        return 4
+    default:
+        return 0
+    }
+}
+
+// snapshot is the serialized form of a Store.
+type snapshot struct {
+    Hosts           []Host          `json:"hosts"`
+    Subdomains      []Subdomain     `json:"subdomains"`
+    Services        []Service       `json:"services"`
+    URLs            []URL           `json:"urls"`
+    Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Export serializes every finding as JSON.
+func (s *Store) Export() ([]byte, error) {
+    return json.Marshal(snapshot{
+        Hosts:           s.Hosts(),
+        Subdomains:      s.Subdomains(),
+        Services:        s.Services(),
+        URLs:            s.URLs(),
+        Vulnerabilities: s.Vulnerabilities(),
+    })
+}
+
+// Import replaces the content of the store with findings produced by Export.
+func (s *Store) Import(data []byte) error {
+    if s == nil {
+        return nil
+    }
+    var snap snapshot
+    if err := json.Unmarshal(data, &snap); err != nil {
+        return err
+    }
+
+    s.Reset()
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for i := range snap.Hosts {
+        h := snap.Hosts[i]
+        s.hosts[h.Address] = &h
+    }
+    for i := range snap.Subdomains {
+        d := snap.Subdomains[i]
+        s.subdomains[d.Name] = &d
+    }
+    for i := range snap.Services {
+        svc := snap.Services[i]
+        s.services[fmt.Sprintf("%s|%d|%s", svc.Host, svc.Port, svc.Protocol)] = &svc
+    }
+    for i := range snap.URLs {
+        u := snap.URLs[i]
+        s.urls[u.URL] = &u
+    }
+    for i := range snap.Vulnerabilities {
+        v := snap.Vulnerabilities[i]
+        s.vulns[v.ID] = &v
+    }
+    return nil
+}
+
+// Table returns headers and rows for one kind of finding: hosts, subdomains,
+// services, urls or vulns.
+func (s *Store) Table(kind string) ([]string, [][]string, error) {
+    var rows [][]string
+    switch kind {
+    case "hosts":
+        for _, h := range s.Hosts() {
+            rows = append(rows, []string{h.Address, h.Module, stamp(h.FirstSeen)})
+        }
+        return []string{"HOST", "MODULE", "FIRST SEEN"}, rows, nil
+    case "subdomains":
+        for _, d := range s.Subdomains() {
+            rows = append(rows, []string{d.Name, d.Module, stamp(d.FirstSeen)})
+        }
+        return []string{"SUBDOMAIN", "MODULE", "FIRST SEEN"}, rows, nil
+    case "services":
+        for _, svc := range s.Services() {
+            rows = append(rows, []string{svc.Host, strconv.Itoa(svc.Port), svc.Protocol, svc.Banner, svc.Module})
+        }
+        return []string{"HOST", "PORT", "PROTO", "BANNER", "MODULE"}, rows, nil
+    case "urls":
+        for _, u := range s.URLs() {
+            status := ""
+            if u.Status != 0 {
+                status = strconv.Itoa(u.Status)
+            }
+            rows = append(rows, []string{u.URL, status, u.Title, u.Module})
+        }
+        return []string{"URL", "STATUS", "TITLE", "MODULE"}, rows, nil
+    case "vulns":
+        for _, v := range s.Vulnerabilities() {
+            rows = append(rows, []string{v.Severity, v.Title, v.Target, v.Evidence, v.Module})
+        }
+        return []string{"SEVERITY", "TITLE", "TARGET", "EVIDENCE", "MODULE"}, rows, nil
+    }
+    return nil, nil, fmt.Errorf("unknown finding type: %s", kind)
+}
+
+// normalize lowercases host names and removes trailing dots.
+func normalize(host string) string {
+    return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// stamp formats a timestamp for tables.
+func stamp(t time.Time) string {
+    if t.IsZero() {
+        return ""
+    }
+    return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,182 @@
+package findings
+
+/*
+Vulnerabilities are written for CI pipelines as SARIF 2.1.0, understood by code
+scanning dashboards, or as JSON lines with one Vulnerability per line:
+
+    err := findings.WriteSARIF(w, store.Vulnerabilities())
+    err := findings.WriteJSONL(w, store.Vulnerabilities())
+
+SARIF rules are the distinct titles of the vulnerabilities; the target of each
+result is its location and the vulnerability ID its fingerprint.
+*/
+
+import (
+    "encoding/json"
+    "io"
+    "regexp"
+    "strings"
+)
+
+const (
+    sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+    sarifVersion = "2.1.0"
+    toolName     = "oblivion"
+    toolURI      = "https://github.com/czz/oblivion"
+)
+
+type sarifLog struct {
+    Schema  string     `json:"$schema"`
+    Version string     `json:"version"`
+    Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+    Tool    sarifTool     `json:"tool"`
+    Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+    Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+    Name           string      `json:"name"`
+    InformationURI string      `json:"informationUri"`
+    Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+    ID                   string                 `json:"id"`
+    Name                 string                 `json:"name"`
+    ShortDescription     sarifText              `json:"shortDescription"`
+    DefaultConfiguration sarifConfig            `json:"defaultConfiguration"`
+    Properties           map[string]interface{} `json:"properties"`
+}
+
+type sarifConfig struct {
+    Level string `json:"level"`
+}
+
+type sarifText struct {
+    Text string `json:"text"`
+}
+
+type sarifResult struct {
+    RuleID              string            `json:"ruleId"`
+    RuleIndex           int               `json:"ruleIndex"`
+    Level               string            `json:"level"`
+    Message             sarifText         `json:"message"`
+    Locations           []sarifLocation   `json:"locations"`
+    PartialFingerprints map[string]string `json:"partialFingerprints"`
+    Properties          Vulnerability     `json:"properties"`
+}
+
+type sarifLocation struct {
+    PhysicalLocation sarifPhysical `json:"physicalLocation"`
+}
+
+type sarifPhysical struct {
+    ArtifactLocation sarifArtifact `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+    URI string `json:"uri"`
+}
+
+// WriteSARIF writes vulnerabilities as a SARIF 2.1.0 log with a single run.
+func WriteSARIF(w io.Writer, vulns []Vulnerability) error {
+    run := sarifRun{
+        Tool:    sarifTool{Driver: sarifDriver{Name: toolName, InformationURI: toolURI, Rules: []sarifRule{}}},
+        Results: []sarifResult{},
+    }
+    rules := make(map[string]int)
+    for _, v := range vulns {
+        id := RuleID(v)
+        index, ok := rules[id]
+        if !ok {
+            index = len(run.Tool.Driver.Rules)
+            rules[id] = index
+            run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+                ID:                   id,
+                Name:                 ruleTitle(v.Title),
+                ShortDescription:     sarifText{Text: ruleTitle(v.Title)},
+                DefaultConfiguration: sarifConfig{Level: sarifLevel(v.Severity)},
+                Properties: map[string]interface{}{
+                    "security-severity": securitySeverity(v.Severity),
+                    "tags":              []string{"security", v.Module},
+                },
+            })
+        }
+
+        message := v.Title + " on " + v.Target
+        if v.Evidence != "" {
+            message += ": " + v.Evidence
+        }
+        if v.ID == "" {
+            v.ID = VulnerabilityID(v.Module, v.Title, v.Target)
+        }
+        run.Results = append(run.Results, sarifResult{
+            RuleID:              id,
+            RuleIndex:           index,
+            Level:               sarifLevel(v.Severity),
+            Message:             sarifText{Text: message},
+            Locations:           []sarifLocation{{PhysicalLocation: sarifPhysical{ArtifactLocation: sarifArtifact{URI: v.Target}}}},
+            PartialFingerprints: map[string]string{"oblivionId/v1": v.ID},
+            Properties:          v,
+        })
+    }
+
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// WriteJSONL writes one vulnerability per line.
+func WriteJSONL(w io.Writer, vulns []Vulnerability) error {
+    encoder := json.NewEncoder(w)
+    for _, v := range vulns {
+        if v.ID == "" {
+            v.ID = VulnerabilityID(v.Module, v.Title, v.Target)
+        }
+        if err := encoder.Encode(v); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// nonRuleChars matches the characters replaced in rule identifiers.
+var nonRuleChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// RuleID returns the SARIF rule of a vulnerability, built from its module and its
+// title without the parenthesized details: "subdomain_takeover/subdomain-takeover".
+func RuleID(v Vulnerability) string {
+    return v.Module + "/" + strings.Trim(nonRuleChars.ReplaceAllString(strings.ToLower(ruleTitle(v.Title)), "-"), "-")
+}
+
+// ruleTitle returns a title without its parenthesized details.
+func ruleTitle(title string) string {
+    if i := strings.Index(title, " ("); i > 0 {
+        return title[:i]
+    }
+    return title
+}
+
+// sarifLevel maps a severity to a SARIF level.
+func sarifLevel(severity string) string {
+    switch SeverityRank(severity) {
+    case 3, 4:
+        return "error"
+    case 2:
+        return "warning"
+    default:
+        return "note"
+    }
+}
+
+// securitySeverity maps a severity to the CVSS-like score code scanning dashboards
+// use to rank security results.
+func securitySeverity(severity string) string {
+    return [...]string{"0.0", "3.0", "5.5", "8.0", "9.5"}[SeverityRank(severity)]
+}
@@ -1,12 +1,17 @@
 package help
 
-import "strings"
+import (
+    "strings"
+
+    "github.com/czz/oblivion/utils/option"
+)
 
 // HelpEntry represents a single help section with a name, description, and table of entries.
 type HelpEntry struct {
     Name        string     // Name of the help topic
     Description string     // Short description of the topic
     Table       [][]string // Help table: typically [option, syntax, description]
+    Options     *option.OptionManager // When set, the table is built from the options
 }
 
 // HelpManager manages multiple HelpEntry objects, allowing registration and retrieval.
@@ -28,6 +33,16 @@ func (h *HelpManager) Register(name, description string, table [][]string) {
     }
 }
 
+// RegisterOptions adds a HelpEntry whose table is built from the options of om each
+// time it is retrieved, so it always matches the registered options.
+func (h *HelpManager) RegisterOptions(name, description string, om *option.OptionManager) {
+    h.entries[name] = &HelpEntry{
+        Name:        name,
+        Description: description,
+        Options:     om,
+    }
+}
+
 // Get retrieves the formatted help table for a given entry name.
 // The output includes a header and a standardized table layout.
 // The first column of each row is always prefixed with exactly two spaces.
@@ -36,6 +51,9 @@ func (h *HelpManager) Get(name string) ([][]string, bool) {
     if !ok {
         return nil, false
     }
+    if entry.Options != nil {
+        return optionsTable(entry), true
+    }
 
     res := [][]string{
         {"Options " + entry.Name, "", ""},
@@ -65,3 +83,46 @@ func (h *HelpManager) List() []*HelpEntry {
     }
     return list
 }
+
+// optionsTable builds the help table of an entry from its options.
+func optionsTable(entry *HelpEntry) [][]string {
+    res := [][]string{
+        {"Options " + entry.Name, "", "", "", ""},
+        {"========" + strings.Repeat("=", len(entry.Name)), "", "", "", ""},
+        {"  Option", "Type", "Syntax", "Default", "Description"},
+        {"  -------", "----", "------", "-------", "-----------"},
+    }
+    for _, opt := range entry.Options.List() {
+        f := opt.Format()
+        res = append(res, []string{"  " + opt.Name, f["type"], opt.Syntax(), f["default"], opt.Description})
+    }
+    return res
+}
+
+// Option returns the detailed help of a single option.
+func Option(opt *option.Option) [][]string {
+    f := opt.Format()
+    res := [][]string{
+        {"Option " + opt.Name, ""},
+        {"=======" + strings.Repeat("=", len(opt.Name)), ""},
+        {"  Description", opt.Description},
+        {"  Type", f["type"]},
+        {"  Syntax", opt.Syntax()},
+        {"  Required", f["required"]},
+        {"  Default", f["default"]},
+        {"  Current", f["value"]},
+    }
+    if len(opt.Enum) > 0 {
+        res = append(res, []string{"  Allowed", strings.Join(opt.Enum, ", ")})
+    }
+    if opt.Doc != "" {
+        for i, line := range strings.Split(opt.Doc, "\n") {
+            label := ""
+            if i == 0 {
+                label = "  Details"
+            }
+            res = append(res, []string{label, line})
+        }
+    }
+    return res
+}
@@ -0,0 +1,67 @@
+package option
+
+import (
+    "sort"
+    "strings"
+    "sync"
+)
+
+// globals holds session-level option values (setg), as raw strings. Every option
+// with the same name inherits them unless it has been set locally.
+//
+// They are process-wide because options are read deep inside module instances,
+// which are built by modules.LoadModules and their factories without any reference
+// to the session. A process runs a single session (the REPL or one oblivion run),
+// which owns the values in its configuration and installs them with SetGlobal.
+var globals = struct {
+    sync.RWMutex
+    values map[string]string
+}{values: make(map[string]string)}
+
+// SetGlobal sets the global value of an option name.
+func SetGlobal(name, value string) {
+    globals.Lock()
+    defer globals.Unlock()
+    globals.values[strings.ToUpper(name)] = value
+}
+
+// UnsetGlobal removes a global value, reporting whether it was set.
+func UnsetGlobal(name string) bool {
+    globals.Lock()
+    defer globals.Unlock()
+    name = strings.ToUpper(name)
+    _, ok := globals.values[name]
+    delete(globals.values, name)
+    return ok
+}
+
+// Global returns the global value of an option name.
+func Global(name string) (string, bool) {
+    globals.RLock()
+    defer globals.RUnlock()
+    value, ok := globals.values[strings.ToUpper(name)]
+    return value, ok
+}
+
+// Globals returns a copy of every global value.
+func Globals() map[string]string {
+    globals.RLock()
+    defer globals.RUnlock()
+    out := make(map[string]string, len(globals.values))
+    for k, v := range globals.values {
+        out[k] = v
+    }
+    return out
+}
+
+// GlobalNames returns the names of the global values, sorted.
+func GlobalNames() []string {
+    globals.RLock()
+    defer globals.RUnlock()
+    names := make([]string, 0, len(globals.values))
+    for k := range globals.values {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,40 @@
+package option
+
+import (
+    "sync"
+    "testing"
+)
+
+// TestGlobalConcurrentReads reads an option inheriting a global from several
+// goroutines, as module workers do; run with -race.
+func TestGlobalConcurrentReads(t *testing.T) {
+    SetGlobal("RACE_PORTS", "1-1024")
+    defer UnsetGlobal("RACE_PORTS")
+
+    m := NewOptionManager()
+    m.Register(NewOption("RACE_PORTS", []int{80}, false, "ports").WithType(TypePorts))
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                if n := len(m.Ints("RACE_PORTS")); n != 1024 {
+                    t.Errorf("got %d ports, want 1024", n)
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    opt, _ := m.Get("RACE_PORTS")
+    if !opt.FromGlobal() || opt.IsSet() {
+        t.Errorf("option should inherit the global without being set")
+    }
+    UnsetGlobal("RACE_PORTS")
+    if n := len(m.Ints("RACE_PORTS")); n != 1 {
+        t.Errorf("got %d ports after unsetg, want the default", n)
+    }
+}
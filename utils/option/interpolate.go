@@ -0,0 +1,116 @@
+package option
+
+/*
+Option values may reference environment variables and stored secrets, resolved
+only when the module reads them:
+
+    set COOKIE session=${SECRET:app_session}
+    set HEADERS Authorization: Bearer ${ENV:API_TOKEN}
+
+The option keeps the reference, so options, profiles and workspaces never hold
+the resolved value.
+*/
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+    "sync"
+)
+
+// refPattern matches ${ENV:NAME} and ${SECRET:name} references.
+var refPattern = regexp.MustCompile(`\$\{(ENV|SECRET):([^}]*)\}`)
+
+// validRefName matches the names accepted in references.
+var validRefName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// secretLookup resolves ${SECRET:name} references; see SetSecretLookup.
+var secretLookup = struct {
+    sync.RWMutex
+    fn func(name string) (string, error)
+}{}
+
+// SetSecretLookup sets the function resolving ${SECRET:name} references, usually
+// the Get of the secrets store. Its errors are reported as they are, so they should
+// name the secret.
+func SetSecretLookup(fn func(name string) (string, error)) {
+    secretLookup.Lock()
+    defer secretLookup.Unlock()
+    secretLookup.fn = fn
+}
+
+// HasRefs reports whether a raw value contains ${ENV:...} or ${SECRET:...} references.
+func HasRefs(raw string) bool {
+    return refPattern.MatchString(raw)
+}
+
+// checkRefs validates the syntax of the references of a raw value without resolving them.
+func checkRefs(raw string) error {
+    for _, m := range refPattern.FindAllStringSubmatch(raw, -1) {
+        if !validRefName.MatchString(m[2]) {
+            return fmt.Errorf("invalid reference %s", m[0])
+        }
+    }
+    return nil
+}
+
+// Interpolate replaces the references of a raw value with the environment variables
+// and secrets they name. A missing variable or secret is an error.
+func Interpolate(raw string) (string, error) {
+    var firstErr error
+    out := refPattern.ReplaceAllStringFunc(raw, func(ref string) string {
+        m := refPattern.FindStringSubmatch(ref)
+        value, err := resolveRef(m[1], m[2])
+        if err != nil && firstErr == nil {
+            firstErr = err
+        }
+        return value
+    })
+    if firstErr != nil {
+        return "", firstErr
+    }
+    return out, nil
+}
+
+// resolveRef returns the value of a single reference.
+func resolveRef(kind, name string) (string, error) {
+    if !validRefName.MatchString(name) {
+        return "", fmt.Errorf("invalid reference ${%s:%s}", kind, name)
+    }
+    if kind == "ENV" {
+        value, ok := os.LookupEnv(name)
+        if !ok {
+            return "", fmt.Errorf("environment variable %s is not set", name)
+        }
+        return value, nil
+    }
+
+    secretLookup.RLock()
+    fn := secretLookup.fn
+    secretLookup.RUnlock()
+    if fn == nil {
+        return "", fmt.Errorf("secret %s: no secrets store", name)
+    }
+    return fn(name)
+}
+
+// Resolved returns the value of the option with its references resolved and parsed
+// according to its type. Options without references return their value as is.
+func (o *Option) Resolved() (interface{}, error) {
+    value, ref, _ := o.current()
+    if ref == "" {
+        return value, nil
+    }
+    raw, err := Interpolate(ref)
+    if err != nil {
+        return nil, err
+    }
+    return o.Parse(raw)
+}
+
+// setRef makes the option hold a raw value with references, resolved when it is read.
+func (o *Option) setRef(raw string) {
+    o.ref = strings.TrimSpace(raw)
+    o.Value = o.ref
+}
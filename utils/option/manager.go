@@ -1,5 +1,11 @@
 package option
 
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
 // OptionManager manages a collection of options.
 type OptionManager struct {
     options   map[string]*Option // A map of options indexed by their name.
@@ -23,10 +29,29 @@ func (m *OptionManager) Register(opt *Option) {
     m.nextPos++
 }
 
-// Get retrieves an option by its name from the OptionManager.
+// Get retrieves an option by its name, ignoring case, from the OptionManager. Options
+// that have not been set locally read the global value of the same name, if any.
 func (m *OptionManager) Get(name string) (*Option, bool) {
-    opt, ok := m.options[name]
-    return opt, ok
+    if opt, ok := m.options[name]; ok {
+        return opt, true
+    }
+    for n, opt := range m.options {
+        if strings.EqualFold(n, name) {
+            return opt, true
+        }
+    }
+    return nil, false
+}
+
+// CopyValues copies the value of every option of src that is also registered in m.
+func (m *OptionManager) CopyValues(src *OptionManager) {
+    for name, opt := range src.options {
+        if dst, ok := m.options[name]; ok {
+            dst.Value = opt.Value
+            dst.ref = opt.ref
+            dst.local = opt.local
+        }
+    }
 }
 
 // List returns all options in registration order.
@@ -43,3 +68,142 @@ func (m *OptionManager) List() []*Option {
     }
     return opts
 }
+
+// Validate checks every option and returns one error per required option that is
+// not set and per option holding an invalid value, in registration order.
+func (m *OptionManager) Validate() []error {
+    var errs []error
+    for _, opt := range m.List() {
+        if opt.isEmpty() {
+            if opt.Required {
+                errs = append(errs, fmt.Errorf("%s is required", opt.Name))
+            }
+            continue
+        }
+        if err := opt.check(); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %v", opt.Name, err))
+        }
+    }
+    return errs
+}
+
+// Set parses raw according to the type of the option and sets it.
+func (m *OptionManager) Set(name, raw string) error {
+    opt, ok := m.Get(name)
+    if !ok {
+        return fmt.Errorf("option not found: %s", name)
+    }
+    name = opt.Name
+    // References are resolved when the option is read, so that secrets are never stored
+    if HasRefs(raw) {
+        if err := checkRefs(raw); err != nil {
+            return fmt.Errorf("%s: %v", name, err)
+        }
+        opt.setRef(raw)
+        opt.local = true
+        return nil
+    }
+    v, err := opt.Parse(raw)
+    if err != nil {
+        return fmt.Errorf("%s: %v", name, err)
+    }
+    opt.Set(v)
+    return nil
+}
+
+// Unset brings an option back to its default (or global) value.
+func (m *OptionManager) Unset(name string) error {
+    opt, ok := m.Get(name)
+    if !ok {
+        return fmt.Errorf("option not found: %s", name)
+    }
+    opt.Reset()
+    return nil
+}
+
+// Reset brings every option back to its default (or global) value.
+func (m *OptionManager) Reset() {
+    for _, opt := range m.options {
+        opt.Reset()
+    }
+}
+
+// Apply sets an option from user input and returns the row printed by the REPL:
+// {name, value} on success, {"Error", message} otherwise. Modules' Set use it.
+func (m *OptionManager) Apply(name, raw string) []string {
+    if err := m.Set(name, raw); err != nil {
+        return []string{"Error", err.Error()}
+    }
+    opt, _ := m.Get(name)
+    return []string{opt.Name, opt.Format()["value"]}
+}
+
+// String returns the value of a string-like option, or "" when it is not a string.
+func (m *OptionManager) String(name string) string {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).(string); ok {
+            return v
+        }
+    }
+    return ""
+}
+
+// Int returns the value of an int option, or 0.
+func (m *OptionManager) Int(name string) int {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).(int); ok {
+            return v
+        }
+    }
+    return 0
+}
+
+// Bool returns the value of a bool option, or false.
+func (m *OptionManager) Bool(name string) bool {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).(bool); ok {
+            return v
+        }
+    }
+    return false
+}
+
+// Duration returns the value of a duration option, or 0.
+func (m *OptionManager) Duration(name string) time.Duration {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).(time.Duration); ok {
+            return v
+        }
+    }
+    return 0
+}
+
+// Strings returns the value of a list or targets option, or nil.
+func (m *OptionManager) Strings(name string) []string {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).([]string); ok {
+            return v
+        }
+    }
+    return nil
+}
+
+// Ints returns the value of a ports option, or nil.
+func (m *OptionManager) Ints(name string) []int {
+    if opt, ok := m.Get(name); ok {
+        if v, ok := resolved(opt).([]int); ok {
+            return v
+        }
+    }
+    return nil
+}
+
+// resolved returns the value of an option with its references resolved, or nil when
+// they cannot be; Validate reports those errors before a run.
+func resolved(opt *Option) interface{} {
+    v, err := opt.Resolved()
+    if err != nil {
+        return nil
+    }
+    return v
+}
@@ -0,0 +1,24 @@
+package option
+
+import "testing"
+
+func TestNamesIgnoreCase(t *testing.T) {
+    m := NewOptionManager()
+    m.Register(NewOption("THREADS", 10, false, "Workers"))
+
+    if row := m.Apply("threads", "20"); row[0] != "THREADS" || row[1] != "20" {
+        t.Fatalf("Apply(threads) = %v", row)
+    }
+    if m.Int("Threads") != 20 {
+        t.Errorf("Int(Threads) = %d, want 20", m.Int("Threads"))
+    }
+    if err := m.Unset("tHrEaDs"); err != nil {
+        t.Fatal(err)
+    }
+    if m.Int("THREADS") != 10 {
+        t.Errorf("THREADS = %d after unset, want 10", m.Int("THREADS"))
+    }
+    if err := m.Set("workers", "1"); err == nil {
+        t.Error("Set of an unknown option succeeded")
+    }
+}
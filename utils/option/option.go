@@ -9,26 +9,82 @@ import (
     "fmt"
     "reflect"
     "strings"
+    "sync"
 )
 
 // Option represents a configuration option with a name, value, required status, and description.
 type Option struct {
     Name        string      // The name of the option.
-    Value       interface{} // The value of the option, which can be of any type.
+    Value       interface{} // The value set on the option, or its default; globals apply when it is read.
     Required    bool        // Indicates if the option is required.
     Description string      // A description of the purpose of the option.
+    Type        Type        // Declared type, used to parse raw values.
+    Enum        []string    // Allowed values of TypeEnum options.
+    Example     string      // Example syntax shown in help, defaults to one based on the type.
+    Doc         string      // Long-form documentation shown by help <OPTION>.
+
+    def       interface{}             // Value the option was created with
+    local     bool                    // Set explicitly on this option
+    ref       string                  // Raw value with ${ENV:..}/${SECRET:..} references, resolved when read
+    validate  func(interface{}) error // Extra check on parsed values
+    inherited *inherited              // Last global value parsed for this option
+}
+
+// inherited caches the parsed global value of an option, so that reading it from
+// many goroutines neither writes the option nor parses the global every time.
+type inherited struct {
+    mu     sync.Mutex
+    parsed bool
+    raw    string
+    value  interface{}
+    err    error
 }
 
 // NewOption creates and returns a new Option with the provided attributes.
+// The type is inferred from the default value and can be changed with WithType.
 func NewOption(name string, value interface{}, required bool, description string) *Option {
     return &Option{
         Name:        name,
         Value:       value,
         Required:    required,
         Description: description,
+        Type:        inferType(value),
+        def:         value,
+        inherited:   &inherited{},
     }
 }
 
+// WithType sets the declared type of the option.
+func (o *Option) WithType(t Type) *Option {
+    o.Type = t
+    return o
+}
+
+// WithEnum makes the option an enum accepting only the given values.
+func (o *Option) WithEnum(values ...string) *Option {
+    o.Type = TypeEnum
+    o.Enum = values
+    return o
+}
+
+// WithExample sets the example syntax shown in the help of the option.
+func (o *Option) WithExample(example string) *Option {
+    o.Example = example
+    return o
+}
+
+// WithDoc sets the long-form documentation of the option.
+func (o *Option) WithDoc(doc string) *Option {
+    o.Doc = doc
+    return o
+}
+
+// WithValidator adds a check run on values after they have been parsed.
+func (o *Option) WithValidator(fn func(interface{}) error) *Option {
+    o.validate = fn
+    return o
+}
+
 // Format formats the option as a map, returning either Go-syntax format (true) or default format (false).
 func (o *Option) Format(f ...bool) map[string]string {
     var c bool = false
@@ -39,30 +95,108 @@ func (o *Option) Format(f ...bool) map[string]string {
     opt := make(map[string]string)
     opt["name"] = o.Name
 
+    value, _, global := o.current()
     if c {
-        opt["value"] = fmt.Sprintf("%#v", o.Value)
+        opt["value"] = fmt.Sprintf("%#v", value)
         opt["required"] = fmt.Sprintf("%#v", o.Required)
     } else {
-        opt["value"] = o.formatValue(o.Value)
+        opt["value"] = o.formatValue(value)
         opt["required"] = fmt.Sprintf("%v", o.Required)
     }
 
     opt["description"] = o.Description
+    opt["type"] = string(o.Type)
+    opt["global"] = fmt.Sprintf("%v", global)
+    opt["default"] = o.formatValue(o.def)
+    opt["changed"] = fmt.Sprintf("%v", !o.IsDefault())
     return opt
 }
 
 // Set allows updating the value of the option
 func (o *Option) Set(v interface{}) {
     o.Value = v
+    o.ref = ""
+    o.local = true
+}
+
+// Reset brings the option back to its default value, or to the global value with
+// the same name if there is one.
+func (o *Option) Reset() {
+    o.Value = o.def
+    o.ref = ""
+    o.local = false
+}
+
+// Default returns the value the option was created with.
+func (o *Option) Default() interface{} {
+    return o.def
+}
+
+// IsDefault reports whether the current value is the same as the default.
+func (o *Option) IsDefault() bool {
+    value, ref, _ := o.current()
+    return ref == "" && rawValue(value) == rawValue(o.def)
+}
+
+// IsSet reports whether the option has been set explicitly, rather than holding its
+// default or a global value.
+func (o *Option) IsSet() bool {
+    return o.local
+}
+
+// FromGlobal reports whether the current value is inherited from a global value.
+func (o *Option) FromGlobal() bool {
+    _, _, global := o.current()
+    return global
 }
 
-// Get returns the value of the option using reflection to dynamically access the value.
-func (o *Option) Get() interface{} {
-    return reflect.ValueOf(o.Value).Interface()
+// current returns the value read from the option and its raw references, if any:
+// the local value when it has been set, otherwise the global value with the same
+// name parsed according to its type, or the default when there is no (valid)
+// global value. It never modifies the option, so modules can read it concurrently.
+func (o *Option) current() (value interface{}, ref string, global bool) {
+    if o.local {
+        return o.Value, o.ref, false
+    }
+    raw, ok := Global(o.Name)
+    if !ok {
+        return o.Value, o.ref, false
+    }
+    if HasRefs(raw) {
+        return strings.TrimSpace(raw), strings.TrimSpace(raw), true
+    }
+    if v, err := o.parseGlobal(raw); err == nil {
+        return v, "", true
+    }
+    return o.Value, o.ref, false
+}
+
+// parseGlobal parses a global value, reusing the last result for the same value.
+func (o *Option) parseGlobal(raw string) (interface{}, error) {
+    c := o.inherited
+    if c == nil {
+        return o.Parse(raw)
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if !c.parsed || c.raw != raw {
+        c.parsed, c.raw = true, raw
+        c.value, c.err = o.Parse(raw)
+    }
+    return c.value, c.err
 }
 
 // formatValue handles the formatting of the value based on its type.
 func (o *Option) formatValue(v interface{}) string {
+    if ports, ok := v.([]int); ok && o.Type == TypePorts {
+        return compressPorts(ports)
+    }
+    if targets, ok := v.([]string); ok && o.Type == TypeTargets {
+        return summarize(targets)
+    }
+    if v == nil {
+        return ""
+    }
     switch reflect.TypeOf(v).Kind() {
     case reflect.Slice:
         return formatSlice(v)
@@ -141,3 +275,32 @@ func formatPointer(v interface{}) string {
     }
     return fmt.Sprintf("%v", val.Elem())
 }
+
+// maxShownTargets is the number of targets listed before summarize abbreviates.
+const maxShownTargets = 5
+
+// summarize lists targets, abbreviating long lists (e.g. expanded CIDRs) to the
+// first few and the total.
+func summarize(targets []string) string {
+    if len(targets) <= maxShownTargets {
+        return strings.Join(targets, ", ")
+    }
+    return fmt.Sprintf("%s, ... (%d targets)", strings.Join(targets[:maxShownTargets], ", "), len(targets))
+}
+
+// compressPorts formats sorted ports collapsing consecutive ones into ranges.
+func compressPorts(ports []int) string {
+    var parts []string
+    for i := 0; i < len(ports); i++ {
+        start := ports[i]
+        for i+1 < len(ports) && ports[i+1] == ports[i]+1 {
+            i++
+        }
+        if ports[i] == start {
+            parts = append(parts, fmt.Sprintf("%d", start))
+        } else {
+            parts = append(parts, fmt.Sprintf("%d-%d", start, ports[i]))
+        }
+    }
+    return strings.Join(parts, ",")
+}
@@ -0,0 +1,286 @@
+package option
+
+import (
+    "fmt"
+    "net/url"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/czz/oblivion/utils/target"
+)
+
+// Type is the declared type of an option, deciding how raw values are parsed.
+type Type string
+
+// Option types. Parsed values are stored as:
+//
+//  TypeString, TypeURL, TypePath, TypeEnum, TypeRegex  string
+//  TypeInt                                             int
+//  TypeBool                                            bool
+//  TypeDuration                                        time.Duration
+//  TypePorts                                           []int
+//  TypeTargets, TypeList                               []string
+const (
+    TypeString   Type = "string"
+    TypeInt      Type = "int"
+    TypeBool     Type = "bool"
+    TypeDuration Type = "duration"
+    TypePorts    Type = "ports"   // 22,80,8000-8100
+    TypeTargets  Type = "targets" // Hosts, addresses, CIDRs, ranges, URLs or files (see utils/target)
+    TypeList     Type = "list"    // Comma separated strings
+    TypeURL      Type = "url"     // http(s) URL
+    TypePath     Type = "path"    // Existing file
+    TypeEnum     Type = "enum"    // One of the allowed values
+    TypeRegex    Type = "regex"   // Regular expression
+)
+
+// Syntax returns the example syntax of the option, from Example or from its type.
+func (o *Option) Syntax() string {
+    if o.Example != "" {
+        return o.Example
+    }
+    switch o.Type {
+    case TypeInt:
+        return "<number>"
+    case TypeBool:
+        return "true or false"
+    case TypeDuration:
+        return "30s or 5m or 1h"
+    case TypePorts:
+        return "80 or 22,80 or 1-1024"
+    case TypeTargets:
+        return "example.com or 10.0.0.0/24,!10.0.0.1 or /path/targets.txt"
+    case TypeList:
+        return "a,b,c"
+    case TypeURL:
+        return "http://127.0.0.1:8080"
+    case TypePath:
+        return "/path/to/file"
+    case TypeEnum:
+        return strings.Join(o.Enum, " or ")
+    case TypeRegex:
+        return "<regexp>"
+    }
+    return "<text>"
+}
+
+// inferType returns the type matching a default value.
+func inferType(v interface{}) Type {
+    switch v.(type) {
+    case int:
+        return TypeInt
+    case bool:
+        return TypeBool
+    case time.Duration:
+        return TypeDuration
+    case []int:
+        return TypePorts
+    case []string:
+        return TypeList
+    }
+    return TypeString
+}
+
+// Parse converts a raw value to the declared type of the option and validates it.
+// An empty value resets string-like and list options to their empty value.
+func (o *Option) Parse(raw string) (interface{}, error) {
+    raw = strings.TrimSpace(raw)
+    v, err := o.parse(raw)
+    if err != nil {
+        return nil, err
+    }
+    if o.validate != nil {
+        if err := o.validate(v); err != nil {
+            return nil, err
+        }
+    }
+    return v, nil
+}
+
+func (o *Option) parse(raw string) (interface{}, error) {
+    switch o.Type {
+    case TypeInt:
+        n, err := strconv.Atoi(raw)
+        if err != nil {
+            return nil, fmt.Errorf("%q is not an integer", raw)
+        }
+        return n, nil
+    case TypeBool:
+        switch strings.ToLower(raw) {
+        case "true", "yes", "on", "1":
+            return true, nil
+        case "false", "no", "off", "0":
+            return false, nil
+        }
+        return nil, fmt.Errorf("%q is not a boolean (true or false)", raw)
+    case TypeDuration:
+        if secs, err := strconv.ParseFloat(raw, 64); err == nil {
+            return time.Duration(secs * float64(time.Second)), nil
+        }
+        d, err := time.ParseDuration(raw)
+        if err != nil {
+            return nil, fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 1h)", raw)
+        }
+        return d, nil
+    case TypePorts:
+        return parsePorts(raw)
+    case TypeTargets:
+        return target.Parse(raw)
+    case TypeList:
+        return splitList(raw), nil
+    case TypeURL:
+        if raw == "" {
+            return raw, nil
+        }
+        u, err := url.Parse(raw)
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+            return nil, fmt.Errorf("%q is not a valid URL (e.g. http://127.0.0.1:8080)", raw)
+        }
+        return raw, nil
+    case TypePath:
+        if raw == "" {
+            return raw, nil
+        }
+        info, err := os.Stat(raw)
+        if err != nil {
+            return nil, fmt.Errorf("file not found: %s", raw)
+        }
+        if info.IsDir() {
+            return nil, fmt.Errorf("%s is a directory", raw)
+        }
+        return raw, nil
+    case TypeEnum:
+        for _, allowed := range o.Enum {
+            if strings.EqualFold(raw, allowed) {
+                return allowed, nil
+            }
+        }
+        return nil, fmt.Errorf("%q is not one of %s", raw, strings.Join(o.Enum, ", "))
+    case TypeRegex:
+        if _, err := regexp.Compile(raw); err != nil {
+            return nil, fmt.Errorf("invalid regexp: %v", err)
+        }
+        return raw, nil
+    }
+    return raw, nil
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(raw string) []string {
+    items := []string{}
+    for _, item := range strings.Split(raw, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+// parsePorts parses a comma separated list of ports and ranges (1-1024), returning
+// them sorted and without duplicates.
+func parsePorts(raw string) ([]int, error) {
+    seen := make(map[int]bool)
+    ports := []int{}
+    add := func(p int) error {
+        if p < 1 || p > 65535 {
+            return fmt.Errorf("port %d out of range 1-65535", p)
+        }
+        if !seen[p] {
+            seen[p] = true
+            ports = append(ports, p)
+        }
+        return nil
+    }
+
+    for _, part := range splitList(raw) {
+        if lo, hi, ok := strings.Cut(part, "-"); ok {
+            start, err1 := strconv.Atoi(strings.TrimSpace(lo))
+            end, err2 := strconv.Atoi(strings.TrimSpace(hi))
+            if err1 != nil || err2 != nil || start > end {
+                return nil, fmt.Errorf("invalid port range %q", part)
+            }
+            for p := start; p <= end; p++ {
+                if err := add(p); err != nil {
+                    return nil, err
+                }
+            }
+            continue
+        }
+        p, err := strconv.Atoi(part)
+        if err != nil {
+            return nil, fmt.Errorf("invalid port %q", part)
+        }
+        if err := add(p); err != nil {
+            return nil, err
+        }
+    }
+    sort.Ints(ports)
+    return ports, nil
+}
+
+// Raw returns the current value in the form accepted by Parse, so that it can be
+// stored and set again later.
+func (o *Option) Raw() string {
+    value, ref, _ := o.current()
+    if ref != "" {
+        return ref
+    }
+    return rawValue(value)
+}
+
+// rawValue formats a parsed value in the form accepted by Parse.
+func rawValue(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case []int:
+        return compressPorts(v)
+    case []string:
+        return strings.Join(v, ",")
+    case time.Duration:
+        return v.String()
+    }
+    return fmt.Sprintf("%v", value)
+}
+
+// isEmpty reports whether the option holds no value: an empty string or list, or nil.
+func (o *Option) isEmpty() bool {
+    value, _, _ := o.current()
+    switch v := value.(type) {
+    case nil:
+        return true
+    case string:
+        return v == ""
+    case []string:
+        return len(v) == 0
+    case []int:
+        return len(v) == 0
+    }
+    return false
+}
+
+// check validates the current value again, e.g. a file that has been removed since
+// the option was set or a default that does not pass the option validator.
+func (o *Option) check() error {
+    if o.isEmpty() {
+        return nil
+    }
+    value, ref, _ := o.current()
+    if ref != "" {
+        _, err := o.Resolved()
+        return err
+    }
+    if path, ok := value.(string); ok && o.Type == TypePath {
+        if _, err := os.Stat(path); err != nil {
+            return fmt.Errorf("file not found: %s", path)
+        }
+    }
+    if o.validate != nil {
+        return o.validate(value)
+    }
+    return nil
+}
@@ -0,0 +1,114 @@
+package progress
+
+import (
+    "fmt"
+    "sync"
+    "time"
+)
+
+// Status is a snapshot of the progress of a module run.
+type Status struct {
+    Done    int64         // Units of work completed
+    Total   int64         // Units of work expected, 0 when unknown
+    Elapsed time.Duration // Time since the run started
+}
+
+// Progresser is implemented by modules able to report how far along a run is.
+type Progresser interface {
+    Progress() Status
+}
+
+// Rate returns the units of work completed per second.
+func (s Status) Rate() float64 {
+    if s.Elapsed <= 0 {
+        return 0
+    }
+    return float64(s.Done) / s.Elapsed.Seconds()
+}
+
+// Percent returns the completion percentage, or -1 when the total is unknown.
+func (s Status) Percent() float64 {
+    if s.Total <= 0 {
+        return -1
+    }
+    return float64(s.Done) * 100 / float64(s.Total)
+}
+
+// String formats the status as "done/total (pct%) rate/s", omitting what is unknown.
+func (s Status) String() string {
+    if s.Total > 0 {
+        return fmt.Sprintf("%d/%d (%.0f%%) %.1f/s", s.Done, s.Total, s.Percent(), s.Rate())
+    }
+    return fmt.Sprintf("%d %.1f/s", s.Done, s.Rate())
+}
+
+// Counter tracks the progress of a run. It is safe for concurrent use and the zero
+// value is ready to use.
+type Counter struct {
+    mu      sync.Mutex
+    done    int64
+    total   int64
+    started time.Time
+    ended   time.Time
+}
+
+// Start resets the counter for a new run of total units (0 when unknown).
+func (c *Counter) Start(total int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.done = 0
+    c.total = int64(total)
+    c.started = time.Now()
+    c.ended = time.Time{}
+}
+
+// AddTotal grows the expected units of work, for runs discovering work as they go.
+func (c *Counter) AddTotal(n int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.total += int64(n)
+}
+
+// Add marks n units of work as completed.
+func (c *Counter) Add(n int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.done += int64(n)
+}
+
+// Inc marks one unit of work as completed.
+func (c *Counter) Inc() {
+    c.Add(1)
+}
+
+// Set replaces the counters, for sources reporting absolute values.
+func (c *Counter) Set(done, total int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.done = int64(done)
+    c.total = int64(total)
+}
+
+// Finish stops the clock, so the rate of a finished run stays stable.
+func (c *Counter) Finish() {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.ended.IsZero() {
+        c.ended = time.Now()
+    }
+}
+
+// Status returns a snapshot of the counter.
+func (c *Counter) Status() Status {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    st := Status{Done: c.done, Total: c.total}
+    switch {
+    case c.started.IsZero():
+    case c.ended.IsZero():
+        st.Elapsed = time.Since(c.started)
+    default:
+        st.Elapsed = c.ended.Sub(c.started)
+    }
+    return st
+}
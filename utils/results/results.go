@@ -0,0 +1,306 @@
+package results
+
+/*
+A Query selects, filters, sorts and pages the rows of a results table:
+
+    q, rest, err := results.ParseArgs([]string{"webspider", "--filter", "title=login", "--sort", "url", "--limit", "20"})
+    // rest ➜ [webspider]
+    view, err := q.Apply([]string{"URL", "TITLE"}, rows)
+    // view.Rows ➜ at most 20 rows whose TITLE matches /login/i, sorted by URL
+
+Columns are given by name (case-insensitive, '_' for spaces) or by 0-based index.
+*/
+
+import (
+    "bytes"
+    "fmt"
+    "net"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Query describes how a results table is displayed.
+type Query struct {
+    Sort    string   // Column to sort by, empty keeps the module order
+    Desc    bool     // Sort in descending order
+    Filters []string // col=regex filters, all must match
+    Grep    string   // Regex matched against every column
+    Limit   int      // Maximum number of rows, 0 for no limit
+    Offset  int      // Rows skipped before the first one shown
+    Columns []string // Columns shown, in this order; empty shows every column
+    Pager   bool     // Show the table through a pager
+}
+
+// View is the result of a Query on a table.
+type View struct {
+    Headers []string   // Headers of the selected columns
+    Rows    [][]string // Rows after filtering, sorting, paging and column selection
+    Matched int        // Rows matching the filters, before paging
+    Total   int        // Rows of the table
+}
+
+// ParseArgs extracts the query flags (--sort, --desc, --filter, --grep, --limit,
+// --offset, --columns, --pager) from args and returns the other arguments.
+func ParseArgs(args []string) (Query, []string, error) {
+    var q Query
+    var rest []string
+    for i := 0; i < len(args); i++ {
+        arg := args[i]
+        if !strings.HasPrefix(arg, "--") {
+            rest = append(rest, arg)
+            continue
+        }
+
+        name, value, hasValue := strings.Cut(arg[2:], "=")
+        switch name {
+        case "desc":
+            q.Desc = true
+            continue
+        case "pager":
+            q.Pager = true
+            continue
+        case "sort", "filter", "grep", "limit", "offset", "columns":
+        default:
+            return q, nil, fmt.Errorf("unknown flag %s", arg)
+        }
+        if !hasValue {
+            if i+1 >= len(args) {
+                return q, nil, fmt.Errorf("missing value for --%s", name)
+            }
+            i++
+            value = args[i]
+        }
+
+        switch name {
+        case "sort":
+            q.Sort = value
+        case "filter":
+            if !strings.Contains(value, "=") {
+                return q, nil, fmt.Errorf("invalid filter %q, expected column=regex", value)
+            }
+            q.Filters = append(q.Filters, value)
+        case "grep":
+            q.Grep = value
+        case "limit", "offset":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 0 {
+                return q, nil, fmt.Errorf("invalid --%s %q", name, value)
+            }
+            if name == "limit" {
+                q.Limit = n
+            } else {
+                q.Offset = n
+            }
+        case "columns":
+            for _, c := range strings.Split(value, ",") {
+                if c = strings.TrimSpace(c); c != "" {
+                    q.Columns = append(q.Columns, c)
+                }
+            }
+        }
+    }
+    return q, rest, nil
+}
+
+// Apply runs the query on a table. headers may be shorter than the rows; missing
+// headers are named after the column index.
+func (q Query) Apply(headers []string, rows [][]string) (View, error) {
+    headers = Complete(headers, rows)
+    view := View{Total: len(rows)}
+
+    type filter struct {
+        column int
+        re     *regexp.Regexp
+    }
+    var filters []filter
+    for _, f := range q.Filters {
+        col, expr, _ := strings.Cut(f, "=")
+        i, err := Column(headers, col)
+        if err != nil {
+            return view, err
+        }
+        re, err := regexp.Compile("(?i)" + expr)
+        if err != nil {
+            return view, fmt.Errorf("invalid filter %q: %v", f, err)
+        }
+        filters = append(filters, filter{column: i, re: re})
+    }
+    var grep *regexp.Regexp
+    if q.Grep != "" {
+        re, err := regexp.Compile("(?i)" + q.Grep)
+        if err != nil {
+            return view, fmt.Errorf("invalid grep %q: %v", q.Grep, err)
+        }
+        grep = re
+    }
+
+    var matched [][]string
+    for _, row := range rows {
+        ok := grep == nil
+        if grep != nil {
+            for _, cell := range row {
+                if grep.MatchString(cell) {
+                    ok = true
+                    break
+                }
+            }
+        }
+        for _, f := range filters {
+            if !ok {
+                break
+            }
+            ok = f.re.MatchString(cell(row, f.column))
+        }
+        if ok {
+            matched = append(matched, row)
+        }
+    }
+    view.Matched = len(matched)
+
+    if q.Sort != "" {
+        i, err := Column(headers, q.Sort)
+        if err != nil {
+            return view, err
+        }
+        sort.SliceStable(matched, func(a, b int) bool {
+            if q.Desc {
+                return Less(cell(matched[b], i), cell(matched[a], i))
+            }
+            return Less(cell(matched[a], i), cell(matched[b], i))
+        })
+    }
+
+    if q.Offset >= len(matched) {
+        matched = nil
+    } else {
+        matched = matched[q.Offset:]
+    }
+    if q.Limit > 0 && q.Limit < len(matched) {
+        matched = matched[:q.Limit]
+    }
+
+    columns := make([]int, len(headers))
+    for i := range columns {
+        columns[i] = i
+    }
+    if len(q.Columns) > 0 {
+        columns = columns[:0]
+        for _, c := range q.Columns {
+            i, err := Column(headers, c)
+            if err != nil {
+                return view, err
+            }
+            columns = append(columns, i)
+        }
+    }
+    for _, i := range columns {
+        view.Headers = append(view.Headers, headers[i])
+    }
+    for _, row := range matched {
+        out := make([]string, len(columns))
+        for j, i := range columns {
+            out[j] = cell(row, i)
+        }
+        view.Rows = append(view.Rows, out)
+    }
+    return view, nil
+}
+
+// Column returns the index of a column given by name or 0-based index.
+func Column(headers []string, col string) (int, error) {
+    for i, h := range headers {
+        if strings.EqualFold(strings.ReplaceAll(h, " ", "_"), strings.ReplaceAll(col, " ", "_")) {
+            return i, nil
+        }
+    }
+    if i, err := strconv.Atoi(col); err == nil && i >= 0 && i < len(headers) {
+        return i, nil
+    }
+    return 0, fmt.Errorf("unknown column %s (available: %s)", col, strings.Join(headers, ", "))
+}
+
+// Less compares two cells: IP addresses byte-wise (so that 10.0.0.9 sorts before
+// 10.0.0.10), durations by length (so that 120ms sorts before 1.5s), numerically
+// when both start with a number (so that "9" and "80/tcp" sort as numbers), as text
+// otherwise.
+func Less(a, b string) bool {
+    if ipA, restA, ok := leadingIP(a); ok {
+        if ipB, restB, ok := leadingIP(b); ok {
+            if c := bytes.Compare(ipA, ipB); c != 0 {
+                return c < 0
+            }
+            return Less(restA, restB)
+        }
+    }
+    if da, err := time.ParseDuration(a); err == nil {
+        if db, err := time.ParseDuration(b); err == nil && da != db {
+            return da < db
+        }
+    }
+    na, okA := leadingNumber(a)
+    nb, okB := leadingNumber(b)
+    if okA && okB && na != nb {
+        return na < nb
+    }
+    return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// leadingIP parses the IP address a cell is or starts with, such as 10.0.0.1,
+// 10.0.0.1:443 or [::1]:80, and returns it in 16-byte form with the rest of the cell.
+func leadingIP(s string) (net.IP, string, bool) {
+    if ip := net.ParseIP(s); ip != nil {
+        return ip.To16(), "", true
+    }
+    if host, port, err := net.SplitHostPort(s); err == nil {
+        if ip := net.ParseIP(host); ip != nil {
+            return ip.To16(), port, true
+        }
+    }
+    return nil, "", false
+}
+
+// leadingNumber parses the number a cell starts with, stopping at a second dot, so
+// that dotted values such as 1.2.3 still compare as numbers.
+func leadingNumber(s string) (float64, bool) {
+    end, dot := 0, false
+    for end < len(s) {
+        c := s[end]
+        if c == '.' {
+            if dot {
+                break
+            }
+            dot = true
+        } else if !(c >= '0' && c <= '9' || end == 0 && c == '-') {
+            break
+        }
+        end++
+    }
+    n, err := strconv.ParseFloat(s[:end], 64)
+    return n, err == nil
+}
+
+// Complete names the columns that have no header after their index.
+func Complete(headers []string, rows [][]string) []string {
+    width := len(headers)
+    for _, row := range rows {
+        if len(row) > width {
+            width = len(row)
+        }
+    }
+    out := append([]string(nil), headers...)
+    for i := len(out); i < width; i++ {
+        out = append(out, strconv.Itoa(i))
+    }
+    return out
+}
+
+// cell returns column i of a row, or "" for short rows.
+func cell(row []string, i int) string {
+    if i < len(row) {
+        return row[i]
+    }
+    return ""
+}
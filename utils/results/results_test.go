@@ -0,0 +1,53 @@
+package results
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestSortAddresses(t *testing.T) {
+    rows := [][]string{
+        {"10.0.0.10", "443"},
+        {"10.0.0.9", "80"},
+        {"192.168.1.2", "22"},
+        {"10.0.0.9", "8080"},
+        {"2.2.2.2", "53"},
+    }
+    view, err := Query{Sort: "HOST"}.Apply([]string{"HOST", "PORT"}, rows)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, row := range view.Rows {
+        got = append(got, row[0])
+    }
+    want := []string{"2.2.2.2", "10.0.0.9", "10.0.0.9", "10.0.0.10", "192.168.1.2"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("sorted hosts %v, want %v", got, want)
+    }
+}
+
+func TestLess(t *testing.T) {
+    cases := []struct {
+        a, b string
+        want bool
+    }{
+        {"10.0.0.9", "10.0.0.10", true},
+        {"10.0.0.10", "10.0.0.9", false},
+        {"10.0.0.9:8080", "10.0.0.9:443", false},
+        {"10.0.0.1:443", "10.0.0.2:80", true},
+        {"::1", "::2", true},
+        {"9", "10", true},
+        {"80/tcp", "443/tcp", true},
+        {"120ms", "1.5s", true},
+        {"2m0s", "90s", false},
+        {"950ms", "1.2s", true},
+        {"2.0.1", "10.1", true},
+        {"alpha", "Beta", true},
+    }
+    for _, c := range cases {
+        if got := Less(c.a, c.b); got != c.want {
+            t.Errorf("Less(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+        }
+    }
+}
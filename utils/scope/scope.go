@@ -0,0 +1,379 @@
+package scope
+
+/*
+A scope is a list of include and exclude entries. Entries can be:
+
+    example.com              the host example.com only
+    *.example.com            every subdomain of example.com (not example.com itself)
+    10.0.0.0/24, 10.0.0.5    IPv4/IPv6 networks and addresses
+    https://example.com/app  URLs with the same scheme, host and port under the path
+                             (/app and /app/..., not /application); the host alone is
+                             allowed too
+
+A target is in scope when it matches an include entry, or when there are no include
+entries, and matches no exclude entry.
+
+s := scope.New()
+s.Add("*.example.com", false)
+s.Add("admin.example.com", true)
+s.Contains("https://www.example.com/login") // ➜ true
+s.Contains("admin.example.com")             // ➜ false
+*/
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "strings"
+    "sync"
+)
+
+// rule is a parsed scope entry.
+type rule struct {
+    entry    string     // Entry as given (normalized)
+    network  *net.IPNet // Address or network
+    wildcard string     // Domain suffix of *.domain entries, with the leading dot
+    host     string     // Exact host, also the host of URL entries
+    url      *url.URL   // URL entry, with its path cleaned and without trailing slash
+}
+
+// parseRule parses a scope entry.
+func parseRule(entry string) (rule, error) {
+    entry = strings.TrimSpace(entry)
+    if entry == "" {
+        return rule{}, fmt.Errorf("empty scope entry")
+    }
+
+    if strings.Contains(entry, "://") {
+        u, err := url.Parse(entry)
+        if err != nil || u.Host == "" {
+            return rule{}, fmt.Errorf("invalid URL %q", entry)
+        }
+        u.Scheme = strings.ToLower(u.Scheme)
+        u.Host = strings.ToLower(u.Host)
+        prefix := *u
+        prefix.Path = strings.TrimSuffix(cleanPath(u.Path), "/")
+        return rule{entry: u.String(), host: u.Hostname(), url: &prefix}, nil
+    }
+
+    if strings.Contains(entry, "/") {
+        _, network, err := net.ParseCIDR(entry)
+        if err != nil {
+            return rule{}, fmt.Errorf("invalid CIDR %q", entry)
+        }
+        return rule{entry: network.String(), network: network}, nil
+    }
+
+    if ip := net.ParseIP(entry); ip != nil {
+        bits := 128
+        if ip.To4() != nil {
+            ip, bits = ip.To4(), 32
+        }
+        return rule{entry: ip.String(), network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+    }
+
+    host := strings.TrimSuffix(strings.ToLower(entry), ".")
+    if strings.HasPrefix(host, "*.") {
+        return rule{entry: host, wildcard: host[1:]}, nil
+    }
+    if strings.ContainsAny(host, "*:/ ") {
+        return rule{}, fmt.Errorf("invalid scope entry %q", entry)
+    }
+    return rule{entry: host, host: host}, nil
+}
+
+// match reports whether a target, split into its URL (if any) and host, matches the rule.
+func (r rule) match(u *url.URL, host string) bool {
+    switch {
+    case r.network != nil:
+        ip := net.ParseIP(host)
+        return ip != nil && r.network.Contains(ip)
+    case r.wildcard != "":
+        return strings.HasSuffix(host, r.wildcard)
+    case r.url != nil:
+        if u == nil {
+            return host == r.host
+        }
+        if u.Scheme != r.url.Scheme || u.Hostname() != r.url.Hostname() || port(u) != port(r.url) {
+            return false
+        }
+        p := cleanPath(u.Path)
+        return r.url.Path == "" || p == r.url.Path || strings.HasPrefix(p, r.url.Path+"/")
+    default:
+        return host == r.host
+    }
+}
+
+// port returns the port of a URL, or the default port of its scheme.
+func port(u *url.URL) string {
+    if p := u.Port(); p != "" {
+        return p
+    }
+    switch u.Scheme {
+    case "http", "ws":
+        return "80"
+    case "https", "wss":
+        return "443"
+    }
+    return ""
+}
+
+// cleanPath resolves the dot segments of a URL path, so /app/../admin is not under /app.
+func cleanPath(p string) string {
+    if p == "" {
+        return "/"
+    }
+    return path.Clean("/" + p)
+}
+
+// split returns the normalized URL (nil for non-URL targets) and host of a target.
+func split(target string) (u *url.URL, host string) {
+    target = strings.TrimSpace(target)
+    if strings.Contains(target, "://") {
+        u, err := url.Parse(target)
+        if err != nil {
+            return nil, ""
+        }
+        u.Scheme = strings.ToLower(u.Scheme)
+        u.Host = strings.ToLower(u.Host)
+        return u, u.Hostname()
+    }
+    if h, _, err := net.SplitHostPort(target); err == nil {
+        target = h
+    }
+    return nil, strings.TrimSuffix(strings.ToLower(target), ".")
+}
+
+// Scope holds include and exclude rules. It is safe for concurrent use.
+type Scope struct {
+    mu      sync.RWMutex
+    include []rule
+    exclude []rule
+}
+
+// New creates an empty scope, which contains every target.
+func New() *Scope {
+    return &Scope{}
+}
+
+// Add adds an include entry, or an exclude entry when exclude is true. Entries
+// already present are ignored.
+func (s *Scope) Add(entry string, exclude bool) error {
+    r, err := parseRule(entry)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    list := &s.include
+    if exclude {
+        list = &s.exclude
+    }
+    for _, existing := range *list {
+        if existing.entry == r.entry {
+            return nil
+        }
+    }
+    *list = append(*list, r)
+    return nil
+}
+
+// Remove removes an include or exclude entry; it reports whether it was found.
+func (s *Scope) Remove(entry string, exclude bool) bool {
+    r, err := parseRule(entry)
+    if err != nil {
+        return false
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    list := &s.include
+    if exclude {
+        list = &s.exclude
+    }
+    for i, existing := range *list {
+        if existing.entry == r.entry {
+            *list = append((*list)[:i], (*list)[i+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+// Clear removes every entry.
+func (s *Scope) Clear() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.include = nil
+    s.exclude = nil
+}
+
+// Load adds the entries of a file, one per line; lines starting with '!' are
+// exclusions and '#' starts a comment. It returns the number of entries read. The
+// file is read into a temporary scope, so on error the scope is left unchanged.
+func (s *Scope) Load(path string) (int, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer f.Close()
+
+    loaded := New()
+    n := 0
+    scanner := bufio.NewScanner(f)
+    for line := 1; scanner.Scan(); line++ {
+        entry := strings.TrimSpace(scanner.Text())
+        if entry == "" || strings.HasPrefix(entry, "#") {
+            continue
+        }
+        exclude := strings.HasPrefix(entry, "!")
+        if err := loaded.Add(strings.TrimPrefix(entry, "!"), exclude); err != nil {
+            return 0, fmt.Errorf("%s:%d: %v", path, line, err)
+        }
+        n++
+    }
+    if err := scanner.Err(); err != nil {
+        return 0, err
+    }
+
+    include, exclude := loaded.Entries()
+    for _, e := range include {
+        s.Add(e, false)
+    }
+    for _, e := range exclude {
+        s.Add(e, true)
+    }
+    return n, nil
+}
+
+// Entries returns the include and exclude entries in the order they were added.
+func (s *Scope) Entries() (include, exclude []string) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    for _, r := range s.include {
+        include = append(include, r.entry)
+    }
+    for _, r := range s.exclude {
+        exclude = append(exclude, r.entry)
+    }
+    return include, exclude
+}
+
+// Empty reports whether the scope has no entries.
+func (s *Scope) Empty() bool {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return len(s.include) == 0 && len(s.exclude) == 0
+}
+
+// Contains reports whether a target (host, address, host:port or URL) is in scope.
+func (s *Scope) Contains(target string) bool {
+    u, host := split(target)
+    if host == "" {
+        return false
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    for _, r := range s.exclude {
+        if r.match(u, host) {
+            return false
+        }
+    }
+    if len(s.include) == 0 {
+        return true
+    }
+    for _, r := range s.include {
+        if r.match(u, host) {
+            return true
+        }
+    }
+    return false
+}
+
+// scopeState is the serialized form of the scope used by Export/Import.
+type scopeState struct {
+    Include []string `json:"include"`
+    Exclude []string `json:"exclude"`
+}
+
+// Export serializes the scope entries.
+func (s *Scope) Export() ([]byte, error) {
+    include, exclude := s.Entries()
+    return json.Marshal(scopeState{Include: include, Exclude: exclude})
+}
+
+// Import replaces the scope entries with those produced by Export.
+func (s *Scope) Import(data []byte) error {
+    var st scopeState
+    if err := json.Unmarshal(data, &st); err != nil {
+        return err
+    }
+    s.Clear()
+    for _, e := range st.Include {
+        if err := s.Add(e, false); err != nil {
+            return err
+        }
+    }
+    for _, e := range st.Exclude {
+        if err := s.Add(e, true); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Reporter is called with every target skipped because it is out of scope.
+type Reporter func(target string)
+
+type contextKey struct{}
+
+type checker struct {
+    scope  *Scope
+    report Reporter
+}
+
+// WithScope returns a context whose module runs are restricted to s; report, if
+// not nil, is called for every target skipped.
+func WithScope(ctx context.Context, s *Scope, report Reporter) context.Context {
+    return context.WithValue(ctx, contextKey{}, checker{scope: s, report: report})
+}
+
+// Allowed reports whether a module running with ctx may contact target, reporting
+// it when it is out of scope. Every target is allowed when ctx carries no scope.
+func Allowed(ctx context.Context, target string) bool {
+    c, ok := ctx.Value(contextKey{}).(checker)
+    if !ok || c.scope == nil || c.scope.Contains(target) {
+        return true
+    }
+    if c.report != nil {
+        c.report(target)
+    }
+    return false
+}
+
+// In is like Allowed but does not report the target.
+func In(ctx context.Context, target string) bool {
+    c, ok := ctx.Value(contextKey{}).(checker)
+    return !ok || c.scope == nil || c.scope.Contains(target)
+}
+
+// CheckRedirect returns an http.Client CheckRedirect function that stops after 10
+// redirects, like the default one, and refuses redirects out of the scope of ctx.
+func CheckRedirect(ctx context.Context) func(*http.Request, []*http.Request) error {
+    return func(req *http.Request, via []*http.Request) error {
+        if len(via) >= 10 {
+            return errors.New("stopped after 10 redirects")
+        }
+        if !Allowed(ctx, req.URL.String()) {
+            return fmt.Errorf("redirect to %s is out of scope", req.URL)
+        }
+        return nil
+    }
+}
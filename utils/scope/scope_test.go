@@ -0,0 +1,98 @@
+package scope
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestURLEntries(t *testing.T) {
+    s := New()
+    for _, entry := range []string{"https://example.com", "https://app.example.org/app"} {
+        if err := s.Add(entry, false); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    cases := []struct {
+        target string
+        want   bool
+    }{
+        {"https://example.com/x", true},
+        {"https://example.com:443/", true},
+        {"example.com", true},
+        {"https://example.com.evil.net/x", false},
+        {"https://example.community/", false},
+        {"http://example.com/", false},
+        {"https://example.com:8443/", false},
+        {"https://app.example.org/app", true},
+        {"https://app.example.org/app/login", true},
+        {"https://app.example.org/application", false},
+        {"https://app.example.org/app/../admin", false},
+    }
+    for _, c := range cases {
+        if got := s.Contains(c.target); got != c.want {
+            t.Errorf("Contains(%q) = %v, want %v", c.target, got, c.want)
+        }
+    }
+}
+
+func TestLoadKeepsScopeOnError(t *testing.T) {
+    dir := t.TempDir()
+    bad := filepath.Join(dir, "bad.txt")
+    good := filepath.Join(dir, "good.txt")
+    os.WriteFile(bad, []byte("new.example.com\n!http://[::1\n"), 0o644)
+    os.WriteFile(good, []byte("# targets\nnew.example.com\n!admin.example.com\n"), 0o644)
+
+    s := New()
+    s.Add("example.org", false)
+    if n, err := s.Load(bad); err == nil || n != 0 {
+        t.Fatalf("Load(bad) = %d, %v, want an error", n, err)
+    }
+    include, exclude := s.Entries()
+    if !reflect.DeepEqual(include, []string{"example.org"}) || len(exclude) != 0 {
+        t.Errorf("scope changed by a failed load: %v %v", include, exclude)
+    }
+
+    if n, err := s.Load(good); err != nil || n != 2 {
+        t.Fatalf("Load(good) = %d, %v", n, err)
+    }
+    include, exclude = s.Entries()
+    if !reflect.DeepEqual(include, []string{"example.org", "new.example.com"}) || !reflect.DeepEqual(exclude, []string{"admin.example.com"}) {
+        t.Errorf("entries after load: %v %v", include, exclude)
+    }
+}
+
+func TestCheckRedirect(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/out":
+            http.Redirect(w, r, "http://out.example.com/", http.StatusFound)
+        case "/in":
+            http.Redirect(w, r, "/ok", http.StatusFound)
+        }
+    }))
+    defer srv.Close()
+
+    s := New()
+    s.Add("127.0.0.1", false)
+    var skipped []string
+    ctx := WithScope(context.Background(), s, func(target string) { skipped = append(skipped, target) })
+    client := &http.Client{CheckRedirect: CheckRedirect(ctx)}
+
+    resp, err := client.Get(srv.URL + "/in")
+    if err != nil {
+        t.Fatalf("redirect in scope: %v", err)
+    }
+    resp.Body.Close()
+    if _, err := client.Get(srv.URL + "/out"); err == nil {
+        t.Error("redirect out of scope was followed")
+    }
+    if len(skipped) != 1 || skipped[0] != "http://out.example.com/" {
+        t.Errorf("skipped %v", skipped)
+    }
+}
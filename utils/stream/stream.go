@@ -0,0 +1,23 @@
+package stream
+
+import "context"
+
+// Emitter receives result rows as soon as a module finds them.
+type Emitter func(row []string)
+
+type emitterKey struct{}
+
+// WithEmitter returns a context carrying emit, to be passed to Module.Run.
+func WithEmitter(ctx context.Context, emit Emitter) context.Context {
+    return context.WithValue(ctx, emitterKey{}, emit)
+}
+
+// Emit sends row to the emitter carried by ctx, if any. Modules streaming their
+// results must emit every row they will return from Run, once, in any order, so
+// callers can display and persist rows while the run is in progress and keep the
+// emitted ones when it is cancelled.
+func Emit(ctx context.Context, row []string) {
+    if emit, ok := ctx.Value(emitterKey{}).(Emitter); ok && emit != nil {
+        emit(row)
+    }
+}
@@ -0,0 +1,293 @@
+package target
+
+/*
+Targets are given as a comma separated list whose entries can be:
+
+    example.com                 hostname
+    10.0.0.1, 2001:db8::1       IPv4/IPv6 address
+    10.0.0.0/24, 2001:db8::/120 CIDR; IPv4 networks of /24 and wider skip their
+                                network and broadcast addresses
+    10.0.0.1-50                 range on the last octet
+    10.0.0.1-10.0.1.20          range between two addresses
+    10.0.0.1:8080, [::1]:80     address or hostname with a port
+    https://example.com/app     URL
+    /path/to/targets.txt        file with one or more entries per line ('#' comments)
+    -                           standard input, read like a file (not at the prompt)
+    !entry                      exclusion, e.g. 10.0.0.0/24,!10.0.0.1 or !excluded.txt
+
+t, err := target.Parse("10.0.0.0/30,!10.0.0.1,example.com")
+// ➜ [10.0.0.0 10.0.0.2 10.0.0.3 example.com]
+*/
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "io"
+    "math/big"
+    "net"
+    "net/url"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// MaxExpand is the largest number of addresses a single CIDR or range may expand to.
+const MaxExpand = 1 << 16
+
+// Stdin is read when a target list contains "-". The interactive session sets it
+// to nil, as standard input belongs to the prompt there, and "-" is then an error.
+var Stdin io.Reader = os.Stdin
+
+// validHost matches hostnames, optionally followed by a port.
+var validHost = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_.-]*[a-z0-9_])?(:[0-9]{1,5})?$`)
+
+// Parse expands a target list into normalized targets: addresses in canonical form,
+// lowercase hostnames and URLs. Duplicates and excluded targets are removed, the
+// order of first appearance is kept.
+func Parse(raw string) ([]string, error) {
+    include, exclude, err := entries(raw)
+    if err != nil {
+        return nil, err
+    }
+
+    excluded := make(map[string]bool)
+    for _, e := range exclude {
+        targets, err := Expand(e)
+        if err != nil {
+            return nil, err
+        }
+        for _, t := range targets {
+            excluded[t] = true
+        }
+    }
+
+    seen := make(map[string]bool)
+    result := []string{}
+    for _, e := range include {
+        targets, err := Expand(e)
+        if err != nil {
+            return nil, err
+        }
+        for _, t := range targets {
+            if !seen[t] && !excluded[t] {
+                seen[t] = true
+                result = append(result, t)
+            }
+        }
+    }
+    return result, nil
+}
+
+// entries splits a target list into included and excluded entries, reading files
+// and standard input.
+func entries(raw string) (include, exclude []string, err error) {
+    for _, item := range strings.Split(raw, ",") {
+        item = strings.TrimSpace(item)
+        negate := strings.HasPrefix(item, "!")
+        item = strings.TrimSpace(strings.TrimPrefix(item, "!"))
+        if item == "" {
+            continue
+        }
+
+        var items []string
+        switch {
+        case item == "-":
+            if Stdin == nil {
+                return nil, nil, fmt.Errorf("standard input (-) can only be read by oblivion run, use a file")
+            }
+            if items, err = readList(Stdin); err != nil {
+                return nil, nil, fmt.Errorf("error reading standard input: %v", err)
+            }
+        case isFile(item):
+            f, err := os.Open(item)
+            if err != nil {
+                return nil, nil, fmt.Errorf("error reading %s: %v", item, err)
+            }
+            items, err = readList(f)
+            f.Close()
+            if err != nil {
+                return nil, nil, fmt.Errorf("error reading %s: %v", item, err)
+            }
+        default:
+            items = []string{item}
+        }
+
+        // In a file, "!entry" lines are exclusions too
+        for _, it := range items {
+            if negate || strings.HasPrefix(it, "!") {
+                exclude = append(exclude, strings.TrimPrefix(it, "!"))
+            } else {
+                include = append(include, it)
+            }
+        }
+    }
+    return include, exclude, nil
+}
+
+// readList reads entries separated by newlines or commas, skipping '#' comments.
+func readList(r io.Reader) ([]string, error) {
+    var items []string
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        for _, item := range strings.Split(line, ",") {
+            if item = strings.TrimSpace(item); item != "" {
+                items = append(items, item)
+            }
+        }
+    }
+    return items, scanner.Err()
+}
+
+// isFile reports whether path names an existing regular file.
+func isFile(path string) bool {
+    info, err := os.Stat(path)
+    return err == nil && info.Mode().IsRegular()
+}
+
+// Expand normalizes a single entry, expanding CIDRs and ranges into addresses.
+func Expand(entry string) ([]string, error) {
+    entry = strings.TrimSpace(entry)
+    switch {
+    case strings.Contains(entry, "://"):
+        u, err := url.Parse(entry)
+        if err != nil || u.Host == "" {
+            return nil, fmt.Errorf("invalid URL %q", entry)
+        }
+        u.Scheme = strings.ToLower(u.Scheme)
+        u.Host = strings.ToLower(u.Host)
+        return []string{u.String()}, nil
+    case strings.Contains(entry, "/"):
+        return expandCIDR(entry)
+    }
+    if ip := net.ParseIP(entry); ip != nil {
+        return []string{ip.String()}, nil
+    }
+    if i := strings.Index(entry, "-"); i > 0 && net.ParseIP(entry[:i]) != nil {
+        return expandRange(entry[:i], entry[i+1:])
+    }
+    if host, port, err := net.SplitHostPort(entry); err == nil {
+        if ip := net.ParseIP(host); ip != nil {
+            if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+                return nil, fmt.Errorf("invalid port in %q", entry)
+            }
+            return []string{net.JoinHostPort(ip.String(), port)}, nil
+        }
+    }
+
+    host := strings.TrimSuffix(strings.ToLower(entry), ".")
+    if !validHost.MatchString(host) {
+        return nil, fmt.Errorf("invalid target %q", entry)
+    }
+    return []string{host}, nil
+}
+
+// Host returns the host of a target: the hostname of a URL, or the target without
+// its port.
+func Host(t string) string {
+    if strings.Contains(t, "://") {
+        if u, err := url.Parse(t); err == nil {
+            return u.Hostname()
+        }
+    }
+    if host, _, err := net.SplitHostPort(t); err == nil {
+        return host
+    }
+    return t
+}
+
+// expandCIDR returns the addresses of an IPv4 or IPv6 network. IPv4 networks of /24
+// and wider leave out their network and broadcast addresses; narrower ones are often
+// a slice of a larger network, where the first and last addresses are hosts.
+func expandCIDR(cidr string) ([]string, error) {
+    ip, ipnet, err := net.ParseCIDR(cidr)
+    if err != nil {
+        return nil, fmt.Errorf("invalid CIDR or missing file %q", cidr)
+    }
+    ones, bits := ipnet.Mask.Size()
+    if bits-ones > 16 {
+        return nil, fmt.Errorf("%s has more than %d addresses", cidr, MaxExpand)
+    }
+    if ip.To4() != nil {
+        ip = ip.To4()
+    }
+
+    var addrs []string
+    for cur := ip.Mask(ipnet.Mask); ipnet.Contains(cur); cur = next(cur) {
+        addrs = append(addrs, cur.String())
+        if isLast(cur) {
+            break
+        }
+    }
+    if len(ip) == net.IPv4len && ones <= 24 {
+        addrs = addrs[1 : len(addrs)-1]
+    }
+    return addrs, nil
+}
+
+// expandRange returns the addresses from start to end. end is either a full address
+// or, for IPv4, the last octet (10.0.0.1-50).
+func expandRange(start, end string) ([]string, error) {
+    from := net.ParseIP(start)
+    if v4 := from.To4(); v4 != nil {
+        from = v4
+    }
+
+    to := net.ParseIP(end)
+    if to == nil {
+        n, err := strconv.Atoi(end)
+        if err != nil || n < 0 || n > 255 || len(from) != net.IPv4len {
+            return nil, fmt.Errorf("invalid range %s-%s", start, end)
+        }
+        to = append(net.IP(nil), from...)
+        to[3] = byte(n)
+    } else if v4 := to.To4(); v4 != nil {
+        to = v4
+    }
+    if len(from) != len(to) || bytes.Compare(from, to) > 0 {
+        return nil, fmt.Errorf("invalid range %s-%s", start, end)
+    }
+
+    size := new(big.Int).Sub(new(big.Int).SetBytes(to), new(big.Int).SetBytes(from))
+    if size.Cmp(big.NewInt(MaxExpand)) >= 0 {
+        return nil, fmt.Errorf("range %s-%s has more than %d addresses", start, end, MaxExpand)
+    }
+
+    var addrs []string
+    for cur := from; ; cur = next(cur) {
+        addrs = append(addrs, cur.String())
+        if bytes.Equal(cur, to) {
+            break
+        }
+    }
+    return addrs, nil
+}
+
+// next returns the address following ip.
+func next(ip net.IP) net.IP {
+    n := append(net.IP(nil), ip...)
+    for i := len(n) - 1; i >= 0; i-- {
+        n[i]++
+        if n[i] != 0 {
+            break
+        }
+    }
+    return n
+}
+
+// isLast reports whether ip is the highest address (all bits set), after which
+// next would wrap around.
+func isLast(ip net.IP) bool {
+    for _, b := range ip {
+        if b != 0xff {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,102 @@
+package target
+
+import (
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParse(t *testing.T) {
+    dir := t.TempDir()
+    list := filepath.Join(dir, "targets.txt")
+    os.WriteFile(list, []byte("# hosts\nexample.com, www.example.com\n!www.example.com\n10.0.0.9\n"), 0o644)
+
+    cases := []struct {
+        raw  string
+        want []string
+    }{
+        {"10.0.0.0/30", []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+        {"10.0.0.16/28,!10.0.0.17", nil}, // Checked by length below
+        {"10.0.0.1-3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+        {"10.0.0.254-10.0.1.1", []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+        {"2001:db8::/126", []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}},
+        {"10.0.0.0/30,!10.0.0.1,Example.COM.", []string{"10.0.0.0", "10.0.0.2", "10.0.0.3", "example.com"}},
+        {"10.0.0.1,10.0.0.1,10.0.0.1-2", []string{"10.0.0.1", "10.0.0.2"}},
+        {"[::1]:80,10.0.0.1:8080,example.com:443", []string{"[::1]:80", "10.0.0.1:8080", "example.com:443"}},
+        {"HTTPS://Example.com/App", []string{"https://example.com/App"}},
+        {list, []string{"example.com", "10.0.0.9"}},
+        {list + ",!10.0.0.9", []string{"example.com"}},
+    }
+    for _, c := range cases {
+        got, err := Parse(c.raw)
+        if err != nil {
+            t.Errorf("Parse(%q): %v", c.raw, err)
+            continue
+        }
+        if c.want == nil {
+            if len(got) != 15 || got[0] != "10.0.0.16" || got[len(got)-1] != "10.0.0.31" {
+                t.Errorf("Parse(%q) = %v, want 10.0.0.16-31 without 10.0.0.17", c.raw, got)
+            }
+            continue
+        }
+        if !reflect.DeepEqual(got, c.want) {
+            t.Errorf("Parse(%q) = %v, want %v", c.raw, got, c.want)
+        }
+    }
+}
+
+func TestWideCIDRSkipsNetworkAndBroadcast(t *testing.T) {
+    got, err := Parse("192.168.1.0/24")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(got) != 254 || got[0] != "192.168.1.1" || got[253] != "192.168.1.254" {
+        t.Errorf("/24 expanded to %d addresses, %s to %s", len(got), got[0], got[len(got)-1])
+    }
+    if got, _ := Parse("10.0.0.0/16"); len(got) != 65534 {
+        t.Errorf("/16 expanded to %d addresses, want 65534", len(got))
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    for _, raw := range []string{
+        "10.0.0.9-3",                   // Reversed range
+        "10.0.0.9-10.0.0.1",            // Reversed range
+        "10.0.0.1-300",                 // Last octet out of bounds
+        "10.0.0.1-::2",                 // Mixed families
+        "10.0.0.0/8",                   // Too many addresses
+        "10.0.0.0/33",                  // Invalid CIDR
+        "10.0.0.1-10.2.0.0",            // Too many addresses
+        "[::1]:http",                   // Invalid port
+        "[::1]:70000",                  // Invalid port
+        "exa mple.com",                 // Invalid hostname
+        "http://",                      // URL without host
+        "!nowhere/list.txt",            // Missing file, taken as a CIDR
+    } {
+        if got, err := Parse(raw); err == nil {
+            t.Errorf("Parse(%q) = %v, want an error", raw, got)
+        }
+    }
+}
+
+func TestStdin(t *testing.T) {
+    defer func(r io.Reader) { Stdin = r }(Stdin)
+
+    Stdin = strings.NewReader("example.com\n10.0.0.1, 10.0.0.2\n")
+    got, err := Parse("-,!10.0.0.2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := []string{"example.com", "10.0.0.1"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Parse(-) = %v, want %v", got, want)
+    }
+
+    // The interactive session disables standard input
+    Stdin = nil
+    if _, err := Parse("-"); err == nil {
+        t.Error("Parse(-) without standard input succeeded")
+    }
+}